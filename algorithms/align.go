@@ -0,0 +1,124 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// Alignment 双输入算子的两张图像大小或位置不同时的处理方式
+type Alignment string
+
+const (
+	AlignReject Alignment = "reject"                 // 大小不同时报错
+	AlignResize Alignment = "resize-second-to-first" // 把第二张图像缩放到第一张的大小
+	AlignCrop   Alignment = "crop-to-intersection"   // 只保留两张图像重叠的区域
+	AlignPad    Alignment = "pad-to-union"           // 扩展到能容纳两张图像的区域, 空白处为黑色
+)
+
+// Alignments lists every supported alignment.
+var Alignments = []string{string(AlignReject), string(AlignResize), string(AlignCrop), string(AlignPad)}
+
+// AlignOptions says how the second image of a two-input operation is
+// matched to the first. OffsetX and OffsetY place the top left corner of
+// the second image relative to the first; they apply to AlignCrop and
+// AlignPad.
+type AlignOptions struct {
+	Mode    Alignment
+	OffsetX int
+	OffsetY int
+}
+
+// alignParams 所有双输入算子共用的对齐参数
+func alignParams() []ParamSpec {
+	return []ParamSpec{
+		{Name: "align", Type: ParamEnum, Options: Alignments, Default: string(AlignReject), Description: "两张图像大小不同时的处理方式"},
+		{Name: "offsetX", Type: ParamInt, Default: 0, Description: "第二张图像左上角相对于第一张的列, 用于 crop-to-intersection 和 pad-to-union", Advanced: true},
+		{Name: "offsetY", Type: ParamInt, Default: 0, Description: "第二张图像左上角相对于第一张的行, 用于 crop-to-intersection 和 pad-to-union", Advanced: true},
+	}
+}
+
+// alignOf 从已解析的参数中读取对齐设置
+func alignOf(p Params) AlignOptions {
+	return AlignOptions{
+		Mode:    Alignment(p.String("align")),
+		OffsetX: p.Int("offsetX"),
+		OffsetY: p.Int("offsetY"),
+	}
+}
+
+// combineAligned 按 align 参数对齐两张输入图像后逐像素合并
+func combineAligned(a *Args, fn pixelFunc2) (image.Image, error) {
+	img1, img2, err := align(subProgress(a.Ctx, 0, 0.5), a.Images[0], a.Images[1], alignOf(a.Params))
+	if err != nil {
+		return nil, err
+	}
+	return combine(subProgress(a.Ctx, 0.5, 1), img1, img2, fn)
+}
+
+// Align returns versions of img1 and img2 with the same size, so that they
+// can be combined pixel by pixel.
+func Align(img1, img2 image.Image, opts AlignOptions) (image.Image, image.Image, error) {
+	return align(context.Background(), img1, img2, opts)
+}
+
+func align(ctx context.Context, img1, img2 image.Image, opts AlignOptions) (image.Image, image.Image, error) {
+	s1, s2 := img1.Bounds().Size(), img2.Bounds().Size()
+	offset := image.Pt(opts.OffsetX, opts.OffsetY)
+
+	switch opts.Mode {
+	case "", AlignReject, AlignResize:
+		if offset != (image.Point{}) {
+			return nil, nil, &ParamError{Param: "offsetX", Reason: fmt.Sprintf("only applies to align=%s and align=%s", AlignCrop, AlignPad)}
+		}
+		if s1 == s2 {
+			return img1, img2, nil
+		}
+		if opts.Mode != AlignResize {
+			return nil, nil, &ParamError{Param: "align", Reason: fmt.Sprintf("the images are %dx%d and %dx%d, choose another alignment to combine them", s1.X, s1.Y, s2.X, s2.Y)}
+		}
+		img2, err := resize(ctx, img2, s1.X, s1.Y, InterpBilinear)
+		return img1, img2, err
+
+	case AlignCrop:
+		// 以第一张图像的左上角为原点
+		r1, r2 := image.Rectangle{Max: s1}, image.Rectangle{Max: s2}.Add(offset)
+		inter := r1.Intersect(r2)
+		if inter.Empty() {
+			return nil, nil, &ParamError{Param: "align", Reason: "the images do not overlap"}
+		}
+		img1, err := crop(ctx, img1, inter)
+		if err != nil {
+			return nil, nil, err
+		}
+		img2, err = crop(ctx, img2, inter.Sub(offset))
+		return img1, img2, err
+
+	case AlignPad:
+		r1, r2 := image.Rectangle{Max: s1}, image.Rectangle{Max: s2}.Add(offset)
+		union := r1.Union(r2)
+		if err := checkOutputSize("offsetX", union.Dx(), union.Dy()); err != nil {
+			return nil, nil, err
+		}
+		return place(img1, union, r1.Min), place(img2, union, r2.Min), nil
+	}
+	return nil, nil, &ParamError{Param: "align", Reason: fmt.Sprintf("unknown alignment %q", opts.Mode)}
+}
+
+// place 把 img 放在 canvas 中 at 处, 返回与 canvas 同样大小、左上角为 (0, 0) 的图像.
+// 其余区域为不透明的黑色, 与邻域算子的常量边界相同
+func place(img image.Image, canvas image.Rectangle, at image.Point) image.Image {
+	src := pixelsOf(img)
+	dst := NewBuffer(image.Rectangle{Max: canvas.Size()}, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	if ch == 4 {
+		for i := 3; i < len(dst.Pix); i += 4 {
+			dst.Pix[i] = 255
+		}
+	}
+	at = at.Sub(canvas.Min)
+	for y := 0; y < src.Height; y++ {
+		copy(dst.Row(at.Y + y)[at.X*ch:], src.Row(y))
+	}
+	return resultOf(img, dst)
+}
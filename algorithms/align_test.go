@@ -0,0 +1,122 @@
+package algorithms
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+// grayRect 返回 w x h 的灰度图像, (x, y) 处的值为 base + 10y + x
+func grayRect(w, h int, base uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Pix[y*w+x] = base + uint8(10*y+x)
+		}
+	}
+	return img
+}
+
+// assertGray 检查 img 的大小和逐行的像素
+func assertGray(t *testing.T, img image.Image, rows ...[]uint8) {
+	t.Helper()
+	if size := img.Bounds().Size(); size.Y != len(rows) || size.X != len(rows[0]) {
+		t.Fatalf("size %v, want %dx%d", size, len(rows[0]), len(rows))
+	}
+	b := pixelsOf(img)
+	for y, want := range rows {
+		if got := b.Row(y); !bytes.Equal(got, want) {
+			t.Fatalf("row %d = %v, want %v", y, got, want)
+		}
+	}
+}
+
+func TestAlignCrop(t *testing.T) {
+	img1, img2 := grayRect(4, 3, 0), grayRect(3, 3, 100)
+
+	// 第二张图像放在 (2, 1): 重叠区域是第一张的 (2,1)-(4,3), 第二张的 (0,0)-(2,2)
+	a, b, err := Align(img1, img2, AlignOptions{Mode: AlignCrop, OffsetX: 2, OffsetY: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGray(t, a, []uint8{12, 13}, []uint8{22, 23})
+	assertGray(t, b, []uint8{100, 101}, []uint8{110, 111})
+
+	// 负的偏移: 第二张图像的 (1,1)-(3,3) 与第一张的 (0,0)-(2,2) 重叠
+	a, b, err = Align(img1, img2, AlignOptions{Mode: AlignCrop, OffsetX: -1, OffsetY: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGray(t, a, []uint8{0, 1}, []uint8{10, 11})
+	assertGray(t, b, []uint8{111, 112}, []uint8{121, 122})
+
+	if _, _, err := Align(img1, img2, AlignOptions{Mode: AlignCrop, OffsetX: 4}); err == nil {
+		t.Fatal("aligned images that do not overlap")
+	}
+}
+
+func TestAlignPad(t *testing.T) {
+	img1, img2 := grayRect(3, 2, 0), grayRect(2, 2, 100)
+
+	// 合并区域为 (0,0)-(4,3), 空白处为 0
+	a, b, err := Align(img1, img2, AlignOptions{Mode: AlignPad, OffsetX: 2, OffsetY: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGray(t, a, []uint8{0, 1, 2, 0}, []uint8{10, 11, 12, 0}, []uint8{0, 0, 0, 0})
+	assertGray(t, b, []uint8{0, 0, 0, 0}, []uint8{0, 0, 100, 101}, []uint8{0, 0, 110, 111})
+
+	// 负的偏移: 合并区域为 (-1,-2)-(3,2), 第一张图像移到 (1, 2)
+	a, b, err = Align(img1, img2, AlignOptions{Mode: AlignPad, OffsetX: -1, OffsetY: -2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGray(t, a, []uint8{0, 0, 0, 0}, []uint8{0, 0, 0, 0}, []uint8{0, 0, 1, 2}, []uint8{0, 10, 11, 12})
+	assertGray(t, b, []uint8{100, 101, 0, 0}, []uint8{110, 111, 0, 0}, []uint8{0, 0, 0, 0}, []uint8{0, 0, 0, 0})
+
+	// 彩色图像的空白处是不透明的黑色
+	rgba := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	copy(rgba.Pix, []uint8{1, 2, 3, 4})
+	a, _, err = Align(rgba, rgba, AlignOptions{Mode: AlignPad, OffsetX: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := pixelsOf(a).Pix, []uint8{1, 2, 3, 4, 0, 0, 0, 255}; !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestAlignRejectAndResize(t *testing.T) {
+	img1, img2 := grayRect(4, 3, 0), grayRect(2, 2, 100)
+	var perr *ParamError
+
+	// 大小相同时原样返回, 不同时报错
+	if a, b, err := Align(img1, img1, AlignOptions{Mode: AlignReject}); err != nil || a != image.Image(img1) || b != image.Image(img1) {
+		t.Fatalf("same size: got %v", err)
+	}
+	if _, _, err := Align(img1, img2, AlignOptions{Mode: AlignReject}); !errors.As(err, &perr) || perr.Param != "align" {
+		t.Fatalf("different sizes: got %v, want a ParamError for align", err)
+	}
+	// 偏移只用于裁剪和扩展
+	if _, _, err := Align(img1, img1, AlignOptions{Mode: AlignResize, OffsetY: 1}); !errors.As(err, &perr) || perr.Param != "offsetX" {
+		t.Fatalf("offset with resize: got %v, want a ParamError for offsetX", err)
+	}
+
+	_, b, err := Align(img1, img2, AlignOptions{Mode: AlignResize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := b.Bounds().Size(); size != image.Pt(4, 3) {
+		t.Fatalf("resized to %v, want 4x3", size)
+	}
+}
+
+func TestAdditionAligned(t *testing.T) {
+	// 通过注册表调用: 扩展后相加, 重叠处为两者之和
+	got, err := Apply("Addition", []image.Image{grayRect(2, 1, 0), grayRect(2, 1, 100)}, Params{"align": string(AlignPad), "offsetX": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGray(t, got, []uint8{0, 101, 101})
+}
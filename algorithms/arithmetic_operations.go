@@ -1,94 +1,87 @@
 package algorithms
 
 import (
-	"bytes"
-	"encoding/base64"
+	"context"
 	"errors"
 	"image"
-	"image/color"
-	"image/jpeg"
-	"mime/multipart"
 )
 
-// ArithmeticOperations performs basic arithmetic operations on two images
-func ArithmeticOperations(file1 multipart.File, file2 multipart.File, operation string) (string, error) {
-	img1, err := decodeImage(file1)
-	if err != nil {
-		return "", err
-	}
+var errImageSize = errors.New("images must have the same dimensions")
 
-	img2, err := decodeImage(file2)
-	if err != nil {
-		return "", err
+func init() {
+	register2 := func(name, label string, fn pixelFunc2) {
+		Register(Operation{
+			Name:     name,
+			Label:    label,
+			Category: CategoryArithmetic,
+			Arity:    2,
+			Params:   alignParams(),
+			Apply: func(a *Args) (image.Image, error) {
+				return combineAligned(a, fn)
+			},
+		})
 	}
 
-	bounds1 := img1.Bounds()
-	bounds2 := img2.Bounds()
+	register2("Addition", "加法", addPixels)
+	register2("Substraction", "减法", subtractPixels)
+	register2("Multiplication", "乘法", multiplyPixels)
+	register2("Division", "除法", dividePixels)
+}
+
+var (
+	addPixels      = channelwise(func(v1, v2 int) int { return v1 + v2 })
+	subtractPixels = channelwise(func(v1, v2 int) int { return v1 - v2 })
+	multiplyPixels = channelwise(func(v1, v2 int) int { return v1 * v2 })
+	divideChannels = channelwise(func(v1, v2 int) int {
+		if v2 == 0 {
+			return v1
+		}
+		return v1 / v2
+	})
+)
 
-	if bounds1 != bounds2 {
-		return "", errors.New("images must have the same dimensions")
+// dividePixels 第二张图像任一颜色通道为 0 时保持第一张图像的像素
+func dividePixels(dst, p1, p2 []uint8) {
+	for i := 0; i < colorChannels(p2); i++ {
+		if p2[i] == 0 {
+			copy(dst, p1) // 保持原有值
+			return
+		}
 	}
+	divideChannels(dst, p1, p2)
+}
 
-	resultImage := image.NewRGBA(bounds1)
+// Add adds two images channel by channel, saturating at 255.
+func Add(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, addPixels)
+}
 
-	for y := 0; y < bounds1.Dy(); y++ {
-		for x := 0; x < bounds1.Dx(); x++ {
-			r1, g1, b1, a1 := img1.At(x, y).RGBA()
-			r2, g2, b2, a2 := img2.At(x, y).RGBA()
+// Subtract subtracts img2 from img1 channel by channel, saturating at 0.
+func Subtract(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, subtractPixels)
+}
 
-			var r, g, b, a uint8
+// Multiply multiplies two images channel by channel, saturating at 255.
+func Multiply(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, multiplyPixels)
+}
 
-			switch operation {
-			case "Addition":
-				r = uint8(clamp(int(r1>>8)+int(r2>>8), 0, 255))
-				g = uint8(clamp(int(g1>>8)+int(g2>>8), 0, 255))
-				b = uint8(clamp(int(b1>>8)+int(b2>>8), 0, 255))
-				a = uint8(clamp(int(a1>>8)+int(a2>>8), 0, 255))
-			case "Substraction":
-				r = uint8(clamp(int(r1>>8)-int(r2>>8), 0, 255))
-				g = uint8(clamp(int(g1>>8)-int(g2>>8), 0, 255))
-				b = uint8(clamp(int(b1>>8)-int(b2>>8), 0, 255))
-				a = uint8(clamp(int(a1>>8)-int(a2>>8), 0, 255))
-			case "Multiplication":
-				r = uint8(clamp(int(r1>>8)*int(r2>>8), 0, 255))
-				g = uint8(clamp(int(g1>>8)*int(g2>>8), 0, 255))
-				b = uint8(clamp(int(b1>>8)*int(b2>>8), 0, 255))
-				a = uint8(clamp(int(a1>>8)*int(a2>>8), 0, 255))
-			case "Division":
-				if r2 == 0 || g2 == 0 || b2 == 0 {
-					r, g, b, a = uint8(r1>>8), uint8(g1>>8), uint8(b1>>8), uint8(a1>>8) // 保持原有值
-				} else {
-					r = uint8(clamp(int(r1>>8)/int(r2>>8), 0, 255))
-					g = uint8(clamp(int(g1>>8)/int(g2>>8), 0, 255))
-					b = uint8(clamp(int(b1>>8)/int(b2>>8), 0, 255))
-					a = uint8(clamp(int(a1>>8)/int(a2>>8), 0, 255))
-				}
-			default:
-				return "", errors.New("unsupported operation")
-			}
+// Divide divides img1 by img2 channel by channel. Pixels where img2 has a
+// zero color channel are copied from img1.
+func Divide(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, dividePixels)
+}
 
-			resultImage.Set(x, y, color.RGBA{r, g, b, a})
+// channelwise 将同一个函数分别作用于每个颜色通道, 结果限制在 0-255.
+// alpha 取自第一张图像, 否则 PNG 等保留透明度的格式会得到透明的结果
+func channelwise(fn func(v1, v2 int) int) pixelFunc2 {
+	return func(dst, p1, p2 []uint8) {
+		n := colorChannels(p1)
+		for i := 0; i < n; i++ {
+			dst[i] = uint8(clamp(fn(int(p1[i]), int(p2[i])), 0, 255))
+		}
+		if len(p1) > n {
+			dst[n] = p1[n]
 		}
 	}
-
-	//processedImagePath := "static/uploads/arithmetic_operations_result.jpg"
-	//out, err := os.Create(processedImagePath)
-	//if err != nil {
-	//	return "", err
-	//}
-	//defer out.Close()
-	//
-	//jpeg.Encode(out, resultImage, nil)
-	//return processedImagePath, nil
-
-	// 将结果图像写入内存中的 buffer
-	var buf bytes.Buffer
-	err = jpeg.Encode(&buf, resultImage, nil)
-	if err != nil {
-		return "", err
-	}
-
-	// 将图像转换为 base64 编码
-	base64Image := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return base64Image, nil
 }
@@ -1,100 +1,69 @@
 package algorithms
 
 import (
-	"bytes"
-	"encoding/base64"
-	"errors"
+	"context"
 	"image"
-	"image/color"
-	"image/jpeg"
-	"mime/multipart"
 )
 
-// BitOperations performs bitwise operations on two images
-func BitOperations(file1 multipart.File, file2 multipart.File, operation string) (string, error) {
-	img1, err := decodeImage(file1)
-	if err != nil {
-		return "", err
-	}
-
-	var img2 image.Image
-	if file2 != nil {
-		img2, err = decodeImage(file2)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	bounds1 := img1.Bounds()
+func init() {
+	Register(Operation{
+		Name:     "Bitwise Not",
+		Label:    "按位取反",
+		Category: CategoryBitwise,
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], notLUT())
+		},
+	})
 
-	if operation != "Bitwise Not" && img2 != nil {
-		bounds2 := img2.Bounds()
-		if bounds1 != bounds2 {
-			return "", errors.New("images must have the same dimensions")
-		}
+	register2 := func(name, label string, fn pixelFunc2) {
+		Register(Operation{
+			Name:     name,
+			Label:    label,
+			Category: CategoryBitwise,
+			Arity:    2,
+			Params:   alignParams(),
+			Apply: func(a *Args) (image.Image, error) {
+				return combineAligned(a, fn)
+			},
+		})
 	}
 
-	resultImage := image.NewRGBA(bounds1)
-
-	for y := 0; y < bounds1.Dy(); y++ {
-		for x := 0; x < bounds1.Dx(); x++ {
-			r1, g1, b1, a1 := img1.At(x, y).RGBA()
-			var r2, g2, b2, a2 uint32
-
-			if operation != "Bitwise Not" && img2 != nil {
-				r2, g2, b2, a2 = img2.At(x, y).RGBA()
-			}
-
-			var r, g, b, a uint8
+	register2("Bitwise And", "按位与", andPixels)
+	register2("Bitwise Or", "按位或", orPixels)
+	register2("Bitwise Xor", "按位异或", xorPixels)
+}
 
-			switch operation {
-			case "Bitwise Not":
-				r = ^uint8(r1 >> 8)
-				g = ^uint8(g1 >> 8)
-				b = ^uint8(b1 >> 8)
-				a = uint8(a1 >> 8)
-				//log.Printf("Pixel at (%d, %d) - r1: %d, g1: %d, b1: %d, a1: %d\n", x, y, r1, g1, b1, a1)
-			case "Bitwise And":
-				r = uint8((r1 >> 8) & (r2 >> 8))
-				g = uint8((g1 >> 8) & (g2 >> 8))
-				b = uint8((b1 >> 8) & (b2 >> 8))
-				a = uint8((a1 >> 8) & (a2 >> 8))
-			case "Bitwise Or":
-				r = uint8((r1 >> 8) | (r2 >> 8))
-				g = uint8((g1 >> 8) | (g2 >> 8))
-				b = uint8((b1 >> 8) | (b2 >> 8))
-				a = uint8((a1 >> 8) | (a2 >> 8))
-			case "Bitwise Xor":
-				r = uint8((r1 >> 8) ^ (r2 >> 8))
-				g = uint8((g1 >> 8) ^ (g2 >> 8))
-				b = uint8((b1 >> 8) ^ (b2 >> 8))
-				a = uint8((a1 >> 8) ^ (a2 >> 8))
-			default:
-				return "", errors.New("unsupported operation")
-			}
+var (
+	andPixels = channelwise(func(v1, v2 int) int { return v1 & v2 })
+	orPixels  = channelwise(func(v1, v2 int) int { return v1 | v2 })
+	xorPixels = channelwise(func(v1, v2 int) int { return v1 ^ v2 })
+)
 
-			resultImage.Set(x, y, color.RGBA{r, g, b, a})
-		}
+func notLUT() *[256]uint8 {
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = ^uint8(i)
 	}
+	return &lut
+}
 
-	//processedImagePath := "static/uploads/bit_operations_result.jpg"
-	//out, err := os.Create(processedImagePath)
-	//if err != nil {
-	//	return "", err
-	//}
-	//defer out.Close()
-	//
-	//jpeg.Encode(out, resultImage, nil)
-	//return processedImagePath, nil
+// BitwiseNot inverts every bit of the color channels of img.
+func BitwiseNot(img image.Image) image.Image {
+	result, _ := applyLUT(context.Background(), img, notLUT())
+	return result
+}
 
-	// 将结果图像写入内存中的 buffer
-	var buf bytes.Buffer
-	jpeg.Encode(&buf, resultImage, nil)
-	if err != nil {
-		return "", err
-	}
+// BitwiseAnd combines two images with a bitwise AND on every channel.
+func BitwiseAnd(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, andPixels)
+}
+
+// BitwiseOr combines two images with a bitwise OR on every channel.
+func BitwiseOr(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, orPixels)
+}
 
-	// 将图像转换为 base64 编码
-	base64Image := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return base64Image, nil
+// BitwiseXor combines two images with a bitwise XOR on every channel.
+func BitwiseXor(img1, img2 image.Image) (image.Image, error) {
+	return combine(context.Background(), img1, img2, xorPixels)
 }
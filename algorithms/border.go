@@ -0,0 +1,188 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// BorderMode 邻域超出图像边界时的取值方式
+type BorderMode string
+
+const (
+	BorderConstant   BorderMode = "constant"    // 使用固定值 BorderValue
+	BorderReplicate  BorderMode = "replicate"   // aaa|abcd|ddd
+	BorderReflect    BorderMode = "reflect"     // cba|abcd|dcb
+	BorderReflect101 BorderMode = "reflect-101" // dcb|abcd|cba
+	BorderWrap       BorderMode = "wrap"        // bcd|abcd|abc
+	BorderCrop       BorderMode = "crop"        // 只输出邻域完全位于图像内的像素, 结果比原图小
+)
+
+// BorderModes lists every supported border mode.
+var BorderModes = []string{
+	string(BorderConstant), string(BorderReplicate), string(BorderReflect),
+	string(BorderReflect101), string(BorderWrap), string(BorderCrop),
+}
+
+// Neighborhood holds the settings shared by every operation that reads a
+// window of pixels around each output pixel.
+type Neighborhood struct {
+	Border BorderMode
+	// BorderValue BorderConstant 使用的颜色值
+	BorderValue uint8
+	// AnchorX, AnchorY 输出像素在窗口中的位置, -1 表示中心 (偶数尺寸时偏向左上)
+	AnchorX int
+	AnchorY int
+}
+
+// DefaultNeighborhood reflects the image at its edges and centers the window.
+var DefaultNeighborhood = Neighborhood{Border: BorderReflect101, AnchorX: -1, AnchorY: -1}
+
+// neighborhoodParams 所有邻域算子共用的参数
+func neighborhoodParams() []ParamSpec {
+	return []ParamSpec{
+		{Name: "border", Type: ParamEnum, Options: BorderModes, Default: string(DefaultNeighborhood.Border), Description: "how pixels outside the image are filled", Advanced: true},
+		{Name: "borderValue", Type: ParamInt, Default: 0, Min: bound(0), Max: bound(255), Description: "fill value for the constant border", Advanced: true},
+		{Name: "anchorX", Type: ParamInt, Default: -1, Min: bound(-1), Description: "window column of the output pixel, -1 for the center", Advanced: true},
+		{Name: "anchorY", Type: ParamInt, Default: -1, Min: bound(-1), Description: "window row of the output pixel, -1 for the center", Advanced: true},
+	}
+}
+
+// neighborhoodOf 从已解析的参数中读取邻域设置
+func neighborhoodOf(p Params) Neighborhood {
+	return Neighborhood{
+		Border:      BorderMode(p.String("border")),
+		BorderValue: uint8(p.Int("borderValue")),
+		AnchorX:     p.Int("anchorX"),
+		AnchorY:     p.Int("anchorY"),
+	}
+}
+
+// anchor 返回 width x height 窗口的锚点
+func (n Neighborhood) anchor(width, height int) (int, int, error) {
+	ax, ay := n.AnchorX, n.AnchorY
+	if ax < 0 {
+		ax = (width - 1) / 2
+	}
+	if ay < 0 {
+		ay = (height - 1) / 2
+	}
+	if ax >= width {
+		return 0, 0, &ParamError{Param: "anchorX", Reason: fmt.Sprintf("must be below the window width %d", width)}
+	}
+	if ay >= height {
+		return 0, 0, &ParamError{Param: "anchorY", Reason: fmt.Sprintf("must be below the window height %d", height)}
+	}
+	return ax, ay, nil
+}
+
+// pad returns src extended so that the width x height window of output pixel
+// (x, y) starts at (x, y) of the returned buffer, and the bounds of the
+// output image. With BorderCrop nothing is added and the output shrinks.
+func (n Neighborhood) pad(ctx context.Context, src *Buffer, width, height int) (*Buffer, image.Rectangle, error) {
+	ax, ay, err := n.anchor(width, height)
+	if err != nil {
+		return nil, image.Rectangle{}, err
+	}
+
+	if n.Border == BorderCrop {
+		if width > src.Width || height > src.Height {
+			return nil, image.Rectangle{}, &ParamError{Param: "border", Reason: fmt.Sprintf("crop needs an image of at least %dx%d", width, height)}
+		}
+		// 不用 image.Rect, 它会交换颠倒的坐标
+		out := image.Rectangle{
+			Min: image.Pt(ax, ay),
+			Max: image.Pt(src.Width-(width-1-ax), src.Height-(height-1-ay)),
+		}
+		return src, out.Add(src.Rect.Min), nil
+	}
+
+	switch n.Border {
+	case "":
+		n.Border = DefaultNeighborhood.Border
+	case BorderConstant, BorderReplicate, BorderReflect, BorderReflect101, BorderWrap:
+	default:
+		return nil, image.Rectangle{}, &ParamError{Param: "border", Reason: fmt.Sprintf("unknown border mode %q", n.Border)}
+	}
+
+	padRect := image.Rect(0, 0, src.Width+width-1, src.Height+height-1).Add(src.Rect.Min.Sub(image.Pt(ax, ay)))
+	padded := NewBuffer(padRect, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+
+	// 填充值: 颜色通道为 BorderValue, alpha 不透明
+	fill := make([]uint8, ch)
+	for c := range fill {
+		fill[c] = n.BorderValue
+	}
+	if ch == 4 {
+		fill[3] = 255
+	}
+
+	err = parallelRows(ctx, padded.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := padded.Row(y)
+			sy, ok := n.Border.index(y-ay, src.Height)
+			if !ok {
+				for i := 0; i < len(out); i += ch {
+					copy(out[i:i+ch], fill)
+				}
+				continue
+			}
+			in := src.Row(sy)
+			copy(out[ax*ch:], in)
+			for x := 0; x < padded.Width; x++ {
+				if x >= ax && x < ax+src.Width {
+					continue
+				}
+				if sx, ok := n.Border.index(x-ax, src.Width); ok {
+					copy(out[x*ch:(x+1)*ch], in[sx*ch:])
+				} else {
+					copy(out[x*ch:(x+1)*ch], fill)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, image.Rectangle{}, err
+	}
+	return padded, src.Rect, nil
+}
+
+// index 将可能越界的坐标 i 映射到 [0, n), BorderConstant 越界时返回 false
+func (mode BorderMode) index(i, n int) (int, bool) {
+	if i >= 0 && i < n {
+		return i, true
+	}
+	switch mode {
+	case BorderReplicate:
+		return min(max(i, 0), n-1), true
+	case BorderReflect:
+		i = mod(i, 2*n)
+		if i >= n {
+			i = 2*n - 1 - i
+		}
+		return i, true
+	case BorderReflect101:
+		if n == 1 {
+			return 0, true
+		}
+		period := 2*n - 2
+		i = mod(i, period)
+		if i >= n {
+			i = period - i
+		}
+		return i, true
+	case BorderWrap:
+		return mod(i, n), true
+	}
+	return 0, false
+}
+
+// mod 返回非负的余数
+func mod(a, b int) int {
+	a %= b
+	if a < 0 {
+		a += b
+	}
+	return a
+}
@@ -0,0 +1,350 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Layout 像素在缓冲区中的排列方式
+type Layout int
+
+const (
+	// Interleaved 所有通道交错存放在 Pix 中, 例如 RGBARGBA...
+	Interleaved Layout = iota
+	// Planar 每个通道单独存放在 Planes[c] 中
+	Planar
+)
+
+// ColorModel 缓冲区中通道的含义
+type ColorModel int
+
+const (
+	ModelRGBA  ColorModel = iota // 预乘 alpha 的 RGBA, 与 *image.RGBA 相同
+	ModelNRGBA                   // 非预乘 alpha 的 RGBA, 与 *image.NRGBA 相同
+	ModelGray                    // 单通道灰度
+	ModelYCbCr                   // 三个平面的 Y, Cb, Cr
+)
+
+// Buffer is the normalized 8-bit pixel storage used by the algorithms.
+//
+// Interleaved buffers keep row y in Pix[y*Stride:], with Channels bytes per
+// pixel. Planar buffers keep row y of channel c in Planes[c][y*Stride:].
+// Buffers created from *image.RGBA, *image.NRGBA, *image.Gray and 4:4:4
+// *image.YCbCr share memory with the image.
+type Buffer struct {
+	Width    int
+	Height   int
+	Channels int
+	Stride   int
+	Layout   Layout
+	Model    ColorModel
+	Pix      []uint8
+	Planes   [][]uint8
+	// Rect 原图像的坐标范围, 转换回 image.Image 时保留
+	Rect image.Rectangle
+}
+
+// FloatBuffer is the float32 counterpart of Buffer, used where intermediate
+// values leave the 0-255 range. Values keep the 0-255 scale.
+type FloatBuffer struct {
+	Width    int
+	Height   int
+	Channels int
+	Stride   int
+	Layout   Layout
+	Model    ColorModel
+	Pix      []float32
+	Planes   [][]float32
+	Rect     image.Rectangle
+}
+
+// NewBuffer allocates a zeroed buffer covering rect.
+func NewBuffer(rect image.Rectangle, channels int, layout Layout, model ColorModel) *Buffer {
+	w, h := rect.Dx(), rect.Dy()
+	b := &Buffer{Width: w, Height: h, Channels: channels, Layout: layout, Model: model, Rect: rect}
+	if layout == Planar {
+		b.Stride = w
+		b.Planes = make([][]uint8, channels)
+		for c := range b.Planes {
+			b.Planes[c] = make([]uint8, w*h)
+		}
+	} else {
+		b.Stride = w * channels
+		b.Pix = make([]uint8, w*h*channels)
+	}
+	return b
+}
+
+// NewFloatBuffer allocates a zeroed float buffer covering rect.
+func NewFloatBuffer(rect image.Rectangle, channels int, layout Layout, model ColorModel) *FloatBuffer {
+	w, h := rect.Dx(), rect.Dy()
+	f := &FloatBuffer{Width: w, Height: h, Channels: channels, Layout: layout, Model: model, Rect: rect}
+	if layout == Planar {
+		f.Stride = w
+		f.Planes = make([][]float32, channels)
+		for c := range f.Planes {
+			f.Planes[c] = make([]float32, w*h)
+		}
+	} else {
+		f.Stride = w * channels
+		f.Pix = make([]float32, w*h*channels)
+	}
+	return f
+}
+
+// BufferOf wraps img in a Buffer, without copying for *image.RGBA,
+// *image.NRGBA, *image.Gray and 4:4:4 *image.YCbCr. Binary masks become one
+// channel gray buffers; other images are converted to RGBA once.
+func BufferOf(img image.Image) *Buffer {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return interleavedOf(img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y):], img.Stride, 4, ModelRGBA, img.Rect)
+	case *image.NRGBA:
+		return interleavedOf(img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y):], img.Stride, 4, ModelNRGBA, img.Rect)
+	case *image.Gray:
+		return interleavedOf(img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y):], img.Stride, 1, ModelGray, img.Rect)
+	case *image.YCbCr:
+		if img.SubsampleRatio == image.YCbCrSubsampleRatio444 && img.YStride == img.CStride {
+			yOff, cOff := img.YOffset(img.Rect.Min.X, img.Rect.Min.Y), img.COffset(img.Rect.Min.X, img.Rect.Min.Y)
+			return &Buffer{
+				Width: img.Rect.Dx(), Height: img.Rect.Dy(), Channels: 3, Stride: img.YStride,
+				Layout: Planar, Model: ModelYCbCr, Rect: img.Rect,
+				Planes: [][]uint8{img.Y[yOff:], img.Cb[cOff:], img.Cr[cOff:]},
+			}
+		}
+	case *image.Paletted:
+		// 二值掩码转为单通道灰度, 不必展开为 RGBA
+		if IsMask(img) {
+			return maskBuffer(img)
+		}
+	}
+	return BufferOf(toRGBA(img))
+}
+
+func interleavedOf(pix []uint8, stride, channels int, model ColorModel, rect image.Rectangle) *Buffer {
+	return &Buffer{
+		Width: rect.Dx(), Height: rect.Dy(), Channels: channels, Stride: stride,
+		Layout: Interleaved, Model: model, Pix: pix, Rect: rect,
+	}
+}
+
+// LumaOf returns the luminance of img as a one channel buffer. Gray images
+// and the Y plane of YCbCr images are used without copying.
+func LumaOf(img image.Image) *Buffer {
+	switch img := img.(type) {
+	case *image.Gray:
+		return BufferOf(img)
+	case *image.YCbCr:
+		return interleavedOf(img.Y[img.YOffset(img.Rect.Min.X, img.Rect.Min.Y):], img.YStride, 1, ModelGray, img.Rect)
+	case *image.Paletted:
+		if IsMask(img) {
+			return maskBuffer(img)
+		}
+	}
+
+	src := BufferOf(img).Interleaved()
+	luma := NewBuffer(src.Rect, 1, Interleaved, ModelGray)
+	parallelRows(context.Background(), src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := src.Row(y), luma.Row(y)
+			for x := range out {
+				p := in[x*src.Channels:]
+				out[x] = luminance(p[0], p[1], p[2])
+			}
+		}
+	})
+	return luma
+}
+
+// luminance 与 color.GrayModel 使用相同的系数
+func luminance(r, g, b uint8) uint8 {
+	return uint8((19595*uint32(r) + 38470*uint32(g) + 7471*uint32(b) + 1<<15) >> 16)
+}
+
+// Row returns row y of an interleaved buffer.
+func (b *Buffer) Row(y int) []uint8 {
+	start := y * b.Stride
+	return b.Pix[start : start+b.Width*b.Channels]
+}
+
+// PlaneRow returns row y of channel c of a planar buffer.
+func (b *Buffer) PlaneRow(c, y int) []uint8 {
+	start := y * b.Stride
+	return b.Planes[c][start : start+b.Width]
+}
+
+// NewLike allocates an empty buffer with the same size, channels, layout
+// and color model.
+func (b *Buffer) NewLike() *Buffer {
+	return NewBuffer(b.Rect, b.Channels, b.Layout, b.Model)
+}
+
+// Interleaved returns b with interleaved channels. Planar YCbCr buffers are
+// converted to RGBA; interleaved buffers are returned as is.
+func (b *Buffer) Interleaved() *Buffer {
+	if b.Layout == Interleaved {
+		return b
+	}
+
+	out := NewBuffer(b.Rect, 4, Interleaved, ModelRGBA)
+	parallelRows(context.Background(), b.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := out.Row(y)
+			for x := 0; x < b.Width; x++ {
+				i := y*b.Stride + x
+				p := row[x*4 : x*4+4]
+				if b.Model == ModelYCbCr {
+					p[0], p[1], p[2] = color.YCbCrToRGB(b.Planes[0][i], b.Planes[1][i], b.Planes[2][i])
+				} else {
+					for c := 0; c < b.Channels && c < 3; c++ {
+						p[c] = b.Planes[c][i]
+					}
+				}
+				p[3] = 255
+				if b.Channels == 4 && b.Model != ModelYCbCr {
+					p[3] = b.Planes[3][i]
+				}
+			}
+		}
+	})
+	return out
+}
+
+// Float converts b to a float buffer with the same layout and model.
+func (b *Buffer) Float() *FloatBuffer {
+	f := NewFloatBuffer(b.Rect, b.Channels, b.Layout, b.Model)
+	parallelRows(context.Background(), b.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			if b.Layout == Planar {
+				for c := range b.Planes {
+					in, out := b.PlaneRow(c, y), f.PlaneRow(c, y)
+					for x, v := range in {
+						out[x] = float32(v)
+					}
+				}
+				continue
+			}
+			in, out := b.Row(y), f.Row(y)
+			for x, v := range in {
+				out[x] = float32(v)
+			}
+		}
+	})
+	return f
+}
+
+// Image returns b as an image.Image, sharing memory where the standard
+// library has a matching type.
+func (b *Buffer) Image() image.Image {
+	if b.Layout == Planar {
+		if b.Model == ModelYCbCr {
+			return &image.YCbCr{
+				Y: b.Planes[0], Cb: b.Planes[1], Cr: b.Planes[2],
+				YStride: b.Stride, CStride: b.Stride,
+				SubsampleRatio: image.YCbCrSubsampleRatio444,
+				Rect:           b.Rect,
+			}
+		}
+		return b.Interleaved().Image()
+	}
+
+	// Pix 从 Rect.Min 处的像素开始, 与标准库图像的 PixOffset 约定一致
+	switch b.Model {
+	case ModelGray:
+		return &image.Gray{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+	case ModelNRGBA:
+		return &image.NRGBA{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+	}
+	return &image.RGBA{Pix: b.Pix, Stride: b.Stride, Rect: b.Rect}
+}
+
+// Row returns row y of an interleaved float buffer.
+func (f *FloatBuffer) Row(y int) []float32 {
+	start := y * f.Stride
+	return f.Pix[start : start+f.Width*f.Channels]
+}
+
+// PlaneRow returns row y of channel c of a planar float buffer.
+func (f *FloatBuffer) PlaneRow(c, y int) []float32 {
+	start := y * f.Stride
+	return f.Planes[c][start : start+f.Width]
+}
+
+// Uint8 rounds and clamps f back to an 8-bit buffer.
+func (f *FloatBuffer) Uint8() *Buffer {
+	b := NewBuffer(f.Rect, f.Channels, f.Layout, f.Model)
+	parallelRows(context.Background(), f.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			if f.Layout == Planar {
+				for c := range f.Planes {
+					in, out := f.PlaneRow(c, y), b.PlaneRow(c, y)
+					for x, v := range in {
+						out[x] = clampFloat(v)
+					}
+				}
+				continue
+			}
+			in, out := f.Row(y), b.Row(y)
+			for x, v := range in {
+				out[x] = clampFloat(v)
+			}
+		}
+	})
+	return b
+}
+
+// FloatImage exposes a FloatBuffer as an image.Image so that float results
+// can pass through the pipeline and be encoded as raw float32 data. At shows
+// the first channel as gray, clamped to 0-255.
+type FloatImage struct {
+	*FloatBuffer
+}
+
+// ColorModel implements image.Image.
+func (f *FloatImage) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+// Bounds implements image.Image.
+func (f *FloatImage) Bounds() image.Rectangle {
+	return f.Rect
+}
+
+// At implements image.Image.
+func (f *FloatImage) At(x, y int) color.Color {
+	if !(image.Point{X: x, Y: y}.In(f.Rect)) {
+		return color.Gray{}
+	}
+	x, y = x-f.Rect.Min.X, y-f.Rect.Min.Y
+	if f.Layout == Planar {
+		return color.Gray{Y: clampFloat(f.Planes[0][y*f.Stride+x])}
+	}
+	return color.Gray{Y: clampFloat(f.Pix[y*f.Stride+x*f.Channels])}
+}
+
+// clampFloat 四舍五入并限制在 0-255
+func clampFloat(v float32) uint8 {
+	switch {
+	case v <= 0 || v != v:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// toRGBA 将任意图像转换为 *image.RGBA, 已经是 RGBA 时不复制
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(b)
+	parallelRows(context.Background(), b.Dy(), func(y0, y1 int) {
+		r := image.Rect(b.Min.X, b.Min.Y+y0, b.Max.X, b.Min.Y+y1)
+		draw.Draw(rgba, r, img, r.Min, draw.Src)
+	})
+	return rgba
+}
@@ -0,0 +1,197 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+)
+
+// CannyOptions configures the Canny edge detector.
+type CannyOptions struct {
+	// Sigma 预先高斯平滑的标准差, 0 表示不平滑
+	Sigma float64
+	// Low, High 双阈值, 作用于梯度幅值
+	Low  float64
+	High float64
+	// Auto 为 true 时忽略 Low 和 High, 由梯度幅值的分布选择阈值
+	Auto bool
+	Norm GradientNorm
+	// Neighborhood 平滑和求梯度时的边界处理
+	Neighborhood Neighborhood
+}
+
+// DefaultCannyOptions smooths with σ = 1.4 and picks the thresholds
+// automatically.
+var DefaultCannyOptions = CannyOptions{Sigma: 1.4, Auto: true, Norm: NormL2, Neighborhood: DefaultNeighborhood}
+
+// 自动阈值: 高阈值取梯度幅值的 70% 分位数, 低阈值为高阈值的 0.4 倍
+const (
+	cannyNonEdgeFraction = 0.7
+	cannyLowRatio        = 0.4
+)
+
+func init() {
+	Register(Operation{
+		Name:     "Canny",
+		Label:    "Canny 边缘检测",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "sigma", Type: ParamFloat, Default: DefaultCannyOptions.Sigma, Min: bound(0), Max: bound(10), Description: "高斯平滑的标准差, 0 表示不平滑"},
+			{Name: "low", Type: ParamFloat, Min: bound(0), Description: "低阈值, 与 high 都不填时自动选择"},
+			{Name: "high", Type: ParamFloat, Min: bound(0), Description: "高阈值, 与 low 都不填时自动选择"},
+			{Name: "norm", Type: ParamEnum, Options: []string{string(NormL2), string(NormL1)}, Default: string(NormL2)},
+		}, neighborhoodParams()...),
+		Apply: func(a *Args) (image.Image, error) {
+			opts := CannyOptions{
+				Sigma:        a.Params.Float("sigma"),
+				Norm:         GradientNorm(a.Params.String("norm")),
+				Neighborhood: neighborhoodOf(a.Params),
+			}
+			if a.Params.Has("low") != a.Params.Has("high") {
+				return nil, &ParamError{Param: "high", Reason: "low and high must be given together"}
+			}
+			// 只有两者都不填时才自动选择, 明确传入的 0 仍然是阈值
+			opts.Low, opts.High, opts.Auto = a.Params.Float("low"), a.Params.Float("high"), !a.Params.Has("low")
+			return canny(a.Ctx, a.Images[0], opts)
+		},
+	})
+}
+
+// Canny detects edges in the luminance of img and returns them as a black
+// and white image.
+func Canny(img image.Image, opts CannyOptions) (*image.Gray, error) {
+	return canny(context.Background(), img, opts)
+}
+
+func canny(ctx context.Context, img image.Image, opts CannyOptions) (*image.Gray, error) {
+	if !opts.Auto && opts.Low > opts.High {
+		return nil, &ParamError{Param: "low", Reason: "must not be above high"}
+	}
+
+	// 1. 高斯平滑
+	luma := LumaOf(img).Image()
+	if opts.Sigma > 0 {
+		kernel, err := gaussianKernelOf(opts.Sigma, 0)
+		if err != nil {
+			return nil, err
+		}
+		smoothed, err := convolve(subProgress(ctx, 0, 0.3), luma, kernel, 1, 0, opts.Neighborhood, MethodAuto)
+		if err != nil {
+			return nil, err
+		}
+		luma = smoothed
+	}
+
+	// 2. Sobel 梯度
+	g, err := computeGradient(subProgress(ctx, 0.3, 0.6), luma, GradientOptions{Operator: OperatorSobel, Norm: opts.Norm, Neighborhood: opts.Neighborhood})
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 非极大值抑制
+	thin, err := suppressNonMaxima(subProgress(ctx, 0.6, 0.9), g)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 双阈值和滞后边缘跟踪
+	low, high := opts.Low, opts.High
+	if opts.Auto {
+		low, high = autoThresholds(thin)
+	}
+	edges := hysteresis(thin, float32(low), float32(high))
+	return edges, ctx.Err()
+}
+
+// suppressNonMaxima 只保留沿梯度方向的局部最大值, 使边缘细化为一个像素宽
+func suppressNonMaxima(ctx context.Context, g *Gradient) (*FloatBuffer, error) {
+	mag := g.Magnitude
+	thin := NewFloatBuffer(mag.Rect, 1, Interleaved, ModelGray)
+	at := func(x, y int) float32 {
+		if x < 0 || y < 0 || x >= mag.Width || y >= mag.Height {
+			return 0
+		}
+		return mag.Pix[y*mag.Stride+x]
+	}
+
+	err := parallelRows(ctx, mag.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out, direction := mag.Row(y), thin.Row(y), g.Direction.Row(y)
+			for x, m := range in {
+				if m == 0 {
+					continue
+				}
+				// 方向量化为 0°, 45°, 90°, 135° 四个扇区
+				angle := math.Mod(float64(direction[x])+math.Pi, math.Pi)
+				var dx, dy int
+				switch {
+				case angle < math.Pi/8 || angle >= 7*math.Pi/8:
+					dx, dy = 1, 0
+				case angle < 3*math.Pi/8:
+					dx, dy = 1, 1
+				case angle < 5*math.Pi/8:
+					dx, dy = 0, 1
+				default:
+					dx, dy = -1, 1
+				}
+				if m > at(x-dx, y-dy) && m >= at(x+dx, y+dy) {
+					out[x] = m
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return thin, nil
+}
+
+// autoThresholds 按非零梯度幅值的分布选择阈值
+func autoThresholds(thin *FloatBuffer) (float64, float64) {
+	var values []float32
+	for _, v := range thin.Pix {
+		if v > 0 {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	high := float64(values[int(cannyNonEdgeFraction*float64(len(values)-1))])
+	return cannyLowRatio * high, high
+}
+
+// hysteresis 高于 high 的像素是边缘, 与边缘 8 邻接且高于 low 的像素也是边缘
+func hysteresis(thin *FloatBuffer, low, high float32) *image.Gray {
+	edges := image.NewGray(thin.Rect)
+	w, h := thin.Width, thin.Height
+
+	var stack []int
+	for i, v := range thin.Pix {
+		if v >= high && v > 0 {
+			edges.Pix[i] = 255
+			stack = append(stack, i)
+		}
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := i%w, i/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				j := ny*w + nx
+				if edges.Pix[j] == 0 && thin.Pix[j] >= low && thin.Pix[j] > 0 {
+					edges.Pix[j] = 255
+					stack = append(stack, j)
+				}
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,81 @@
+package algorithms
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// stepImage 左半为 0, 右半为 255 的灰度图像
+func stepImage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := width / 2; x < width; x++ {
+			img.Pix[y*img.Stride+x] = 255
+		}
+	}
+	return img
+}
+
+// countEdges 返回边缘像素的数量
+func countEdges(edges *image.Gray) int {
+	n := 0
+	for _, v := range edges.Pix {
+		if v == 255 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCannyStepEdge(t *testing.T) {
+	edges, err := Canny(stepImage(32, 24), DefaultCannyOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每一行恰好一个边缘像素, 位于台阶处, 所有行相同
+	column := -1
+	for y := 0; y < 24; y++ {
+		var xs []int
+		for x := 0; x < 32; x++ {
+			if edges.GrayAt(x, y).Y == 255 {
+				xs = append(xs, x)
+			}
+		}
+		if len(xs) != 1 || (xs[0] != 15 && xs[0] != 16) {
+			t.Fatalf("row %d: edges at %v, want one pixel at 15 or 16", y, xs)
+		}
+		if column >= 0 && xs[0] != column {
+			t.Fatalf("row %d: edge at %d, row 0 at %d", y, xs[0], column)
+		}
+		column = xs[0]
+	}
+}
+
+func TestCannyExplicitZeroThresholds(t *testing.T) {
+	// 弱噪声加上一个强台阶: 阈值为 0 时保留所有局部最大值
+	img := stepImage(64, 64)
+	rng := rand.New(rand.NewSource(1))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(min(int(img.Pix[i])+rng.Intn(8), 255))
+	}
+
+	auto, err := Apply("Canny", []image.Image{img}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := Apply("Canny", []image.Image{img}, Params{"low": 0.0, "high": 0.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 阈值为 0 时的边缘包含自动阈值的所有边缘, 并且更多
+	a, z := auto.(*image.Gray), zero.(*image.Gray)
+	for i := range a.Pix {
+		if a.Pix[i] == 255 && z.Pix[i] != 255 {
+			t.Fatalf("pixel %d is an edge with automatic thresholds but not with low=0&high=0", i)
+		}
+	}
+	if nAuto, nZero := countEdges(a), countEdges(z); nZero <= nAuto {
+		t.Fatalf("low=0&high=0 gives %d edge pixels, automatic thresholds %d; explicit zeros were treated as automatic", nZero, nAuto)
+	}
+}
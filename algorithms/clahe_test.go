@@ -0,0 +1,66 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClaheLUT(t *testing.T) {
+	// 不裁剪时就是累积分布: 100 出现 3 次, 200 出现 1 次
+	var hist [256]int
+	hist[100], hist[200] = 3, 1
+	lut := claheLUT(&hist, 4, 0)
+	for v, want := range map[int]float32{0: 0, 99: 0, 100: 191.25, 199: 191.25, 200: 255, 255: 255} {
+		if lut[v] != want {
+			t.Errorf("no clip: lut[%d] = %v, want %v", v, lut[v], want)
+		}
+	}
+
+	// 256 个像素都是 10, clipLimit 2 时每级最多 2 个, 超出的 254 个从 0 开始每级分配 1 个
+	hist = [256]int{10: 256}
+	lut = claheLUT(&hist, 256, 2)
+	scale := float32(255) / 256
+	for v, want := range map[int]float32{0: 1 * scale, 9: 10 * scale, 10: 13 * scale, 253: 256 * scale, 255: 255} {
+		if math.Abs(float64(lut[v]-want)) > 1e-3 {
+			t.Errorf("clipped: lut[%d] = %v, want %v", v, lut[v], want)
+		}
+	}
+}
+
+func TestTileWeightAt(t *testing.T) {
+	// 3 个宽为 10 的分块, 中心在 5, 15, 25
+	tests := []struct {
+		pos  int
+		want tileWeight
+	}{
+		{0, tileWeight{0, 0, 0}},
+		{4, tileWeight{0, 0, 0}},
+		{5, tileWeight{0, 1, 0.05}},
+		{14, tileWeight{0, 1, 0.95}},
+		{20, tileWeight{1, 2, 0.55}},
+		{25, tileWeight{2, 2, 0}},
+		{29, tileWeight{2, 2, 0}},
+	}
+	for _, tt := range tests {
+		got := tileWeightAt(tt.pos, 10, 3)
+		if got.t0 != tt.want.t0 || got.t1 != tt.want.t1 || math.Abs(float64(got.w-tt.want.w)) > 1e-6 {
+			t.Errorf("tileWeightAt(%d) = %+v, want %+v", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestCLAHESingleTile(t *testing.T) {
+	// 1x1 分块且不裁剪时等于按累积分布映射的全局均衡化
+	img := testImages()["gray"]
+	got, err := CLAHE(img, 1, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdf := ComputeHistogram(img).Luminance.CDF()
+	src, dst := pixelsOf(img), pixelsOf(got)
+	for i, v := range src.Pix {
+		if want := clampFloat(float32(cdf[v] * 255)); dst.Pix[i] != want {
+			t.Fatalf("pixel %d: %d maps to %d, want %d", i, v, dst.Pix[i], want)
+		}
+	}
+}
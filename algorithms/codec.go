@@ -0,0 +1,134 @@
+package algorithms
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+// Format 输出图像格式
+type Format string
+
+const (
+	FormatPNG  Format = "png"
+	FormatJPEG Format = "jpeg"
+	FormatGIF  Format = "gif"
+	FormatBMP  Format = "bmp"
+	FormatTIFF Format = "tiff"
+	// FormatFloat32 原始的 little-endian float32 数据, 按行存放, 通道交错
+	FormatFloat32 Format = "float32"
+)
+
+// Formats lists every supported output format.
+var Formats = []Format{FormatPNG, FormatJPEG, FormatGIF, FormatBMP, FormatTIFF, FormatFloat32}
+
+// DefaultQuality is the JPEG quality used when none is given.
+const DefaultQuality = jpeg.DefaultQuality
+
+// EncodeOptions controls how a result image is encoded.
+type EncodeOptions struct {
+	Format Format
+	// Quality 仅对 JPEG 有效, 取值 1-100, 0 表示默认值
+	Quality int
+}
+
+// ParseFormat parses a format name such as "png" or "jpg".
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "png":
+		return FormatPNG, nil
+	case "jpeg", "jpg":
+		return FormatJPEG, nil
+	case "gif":
+		return FormatGIF, nil
+	case "bmp":
+		return FormatBMP, nil
+	case "tiff", "tif":
+		return FormatTIFF, nil
+	case "float32", "raw":
+		return FormatFloat32, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q", name)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatFloat32 {
+		return "application/octet-stream"
+	}
+	return "image/" + string(f)
+}
+
+// Decode reads an image in any registered format and returns it together
+// with the format name.
+func Decode(r io.Reader) (image.Image, string, error) {
+	return image.Decode(r)
+}
+
+// Encode writes img to w in the requested format. The zero value of opts
+// encodes a JPEG at default quality.
+func Encode(w io.Writer, img image.Image, opts EncodeOptions) error {
+	quality := opts.Quality
+	if quality == 0 {
+		quality = DefaultQuality
+	}
+	if quality < 1 || quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100, got %d", quality)
+	}
+
+	switch opts.Format {
+	case FormatJPEG, "":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	case FormatBMP:
+		return bmp.Encode(w, img)
+	case FormatTIFF:
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate})
+	case FormatFloat32:
+		return encodeFloat32(w, img)
+	}
+	return fmt.Errorf("unsupported output format %q", opts.Format)
+}
+
+// encodeFloat32 写出原始 float32 数据. FloatImage 保留所有通道和原始数值,
+// 其它图像写出 0-255 的通道值
+func encodeFloat32(w io.Writer, img image.Image) error {
+	var f *FloatBuffer
+	if fi, ok := img.(*FloatImage); ok {
+		f = fi.FloatBuffer
+	} else {
+		f = BufferOf(img).Interleaved().Float()
+	}
+
+	bw := bufio.NewWriter(w)
+	row := make([]byte, 4*f.Width*f.Channels)
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			for c := 0; c < f.Channels; c++ {
+				var v float32
+				if f.Layout == Planar {
+					v = f.Planes[c][y*f.Stride+x]
+				} else {
+					v = f.Pix[y*f.Stride+x*f.Channels+c]
+				}
+				binary.LittleEndian.PutUint32(row[4*(x*f.Channels+c):], math.Float32bits(v))
+			}
+		}
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,72 @@
+package algorithms
+
+import "math"
+
+// sRGB 与 CIE L*a*b* (D65 白点) 之间的转换
+
+// D65 白点
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// srgbToLinear 8 位 sRGB 值对应的线性值, 0-1
+var srgbToLinear = func() (lut [256]float64) {
+	for i := range lut {
+		c := float64(i) / 255
+		if c <= 0.04045 {
+			lut[i] = c / 12.92
+		} else {
+			lut[i] = math.Pow((c+0.055)/1.055, 2.4)
+		}
+	}
+	return lut
+}()
+
+func linearToSRGB(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return clampFloat(float32(c * 255))
+}
+
+// rgbToLab 返回 L (0-100), a 和 b
+func rgbToLab(r, g, b uint8) (float64, float64, float64) {
+	lr, lg, lb := srgbToLinear[r], srgbToLinear[g], srgbToLinear[b]
+	x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / whiteX
+	y := (0.2126729*lr + 0.7151522*lg + 0.0721750*lb) / whiteY
+	z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / whiteZ
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// labToRGB 是 rgbToLab 的逆变换, 超出 sRGB 色域的值被截断
+func labToRGB(l, a, b float64) (uint8, uint8, uint8) {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+	x, y, z := labFInverse(fx)*whiteX, labFInverse(fy)*whiteY, labFInverse(fz)*whiteZ
+	lr := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	lg := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	lb := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return linearToSRGB(lr), linearToSRGB(lg), linearToSRGB(lb)
+}
+
+const labEpsilon = 216.0 / 24389 // (6/29)^3
+
+func labF(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return t*24389/27/116 + 16.0/116
+}
+
+func labFInverse(t float64) float64 {
+	if t3 := t * t * t; t3 > labEpsilon {
+		return t3
+	}
+	return (116*t - 16) * 27 / 24389
+}
@@ -1,129 +1,201 @@
 package algorithms
 
 import (
-	"bytes"
-	"encoding/base64"
-	"errors"
+	"context"
+	"fmt"
 	"image"
-	"image/color"
-	"image/jpeg"
-	"mime/multipart"
 )
 
-// Convolution 通用卷积函数
-func Convolution(file multipart.File, algorithm string) (string, error) {
-	img, err := decodeImage(file)
-	if err != nil {
-		return "", err
+func init() {
+	kernels := []struct {
+		name    string
+		label   string
+		kernel  [][]int
+		divisor int
+	}{
+		{"Convolution - Averaging", "卷积 - 平均", AveragingKernel, 9},
+		{"Convolution - Weighted averaging", "卷积 - 加权平均", WeightedAveragingKernel, 16},
+		{"Convolution - Four Neighbour Laplacian", "卷积 - 四邻域拉普拉斯", FourNeighbourLaplacianKernel, 1},
+		{"Convolution - Eight Neighbour Laplacian", "卷积 - 八邻域拉普拉斯", EightNeighbourLaplacianKernel, 1},
+		{"Convolution - Four Neighbour Laplacian Enhancement", "卷积 - 四邻域拉普拉斯增强", FourNeighbourLaplacianEnhancementKernel, 1},
+		{"Convolution - Eight Neighbour Laplacian Enhancement", "卷积 - 八邻域拉普拉斯增强", EightNeighbourLaplacianEnhancementKernel, 1},
+		{"Convolution - Roberts One", "卷积 - 罗伯茨一", RobertsOneKernel, 1},
+		{"Convolution - Roberts Two", "卷积 - 罗伯茨二", RobertsTwoKernel, 1},
+		{"Convolution - Sobel X", "卷积 - 索贝尔 X", SobelXKernel, 1},
+		{"Convolution - Sobel Y", "卷积 - 索贝尔 Y", SobelYKernel, 1},
 	}
 
-	bounds := img.Bounds()
-	convolutionImage := image.NewRGBA(bounds)
-
-	var kernel [][]int
-	var divisor int
+	for _, k := range kernels {
+		kernel, divisor := IntKernel(k.kernel), float64(k.divisor)
+		Register(Operation{
+			Name:     k.name,
+			Label:    k.label,
+			Category: CategoryConvolution,
+			Params:   neighborhoodParams(),
+			Apply: func(a *Args) (image.Image, error) {
+				return convolve(a.Ctx, a.Images[0], kernel, divisor, 0, neighborhoodOf(a.Params), MethodAuto)
+			},
+		})
+	}
 
-	// 根据算法选择不同的卷积核
-	switch algorithm {
-	case "Convolution - Averaging":
-		kernel = AveragingKernel
-		divisor = 9
+	Register(Operation{
+		Name:     "Convolution - Custom",
+		Label:    "卷积 - 自定义",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "kernel", Type: ParamKernel, Required: true, Description: "JSON 二维数组, 例如 [[1,2,1],[2,4,2],[1,2,1]]"},
+			{Name: "divisor", Type: ParamFloat, Description: "卷积和除以 divisor, 默认 1"},
+			{Name: "bias", Type: ParamFloat, Default: 0.0, Description: "除法之后加上的偏移量"},
+			{Name: "normalize", Type: ParamBool, Default: false, Description: "以权重之和作为 divisor"},
+			{Name: "method", Type: ParamEnum, Options: ConvolutionMethods, Default: string(MethodAuto), Description: "计算方式, 结果在误差范围内相同", Advanced: true},
+		}, neighborhoodParams()...),
+		Apply: func(a *Args) (image.Image, error) {
+			kernel := a.Params.Kernel("kernel")
+			divisor, err := kernelDivisor(kernel, a.Params)
+			if err != nil {
+				return nil, err
+			}
+			method := ConvolutionMethod(a.Params.String("method"))
+			return convolve(a.Ctx, a.Images[0], kernel, divisor, a.Params.Float("bias"), neighborhoodOf(a.Params), method)
+		},
+	})
+}
 
-	case "Convolution - Weighted averaging":
-		kernel = WeightedAveragingKernel
-		divisor = 16
+// kernelDivisor 根据 divisor 和 normalize 参数确定除数
+func kernelDivisor(kernel *Kernel, params Params) (float64, error) {
+	if params.Bool("normalize") {
+		if params.Has("divisor") {
+			return 0, &ParamError{Param: "divisor", Reason: "cannot be combined with normalize"}
+		}
+		// 权重之和为 0 的核 (例如边缘检测) 无法归一化, 保持原值
+		if sum := kernel.Sum(); sum != 0 {
+			return sum, nil
+		}
+		return 1, nil
+	}
+	if !params.Has("divisor") {
+		return 1, nil
+	}
+	divisor := params.Float("divisor")
+	if divisor == 0 {
+		return 0, &ParamError{Param: "divisor", Reason: "must not be zero"}
+	}
+	return divisor, nil
+}
 
-	case "Convolution - Four Neighbour Laplacian":
-		kernel = FourNeighbourLaplacianKernel
-		divisor = 1
+// Convolve applies an integer kernel and divides every sum by divisor,
+// using DefaultNeighborhood at the edges. divisor must not be zero.
+func Convolve(img image.Image, kernel [][]int, divisor int) (image.Image, error) {
+	return ConvolveKernel(img, IntKernel(kernel), float64(divisor), 0, DefaultNeighborhood)
+}
 
-	case "Convolution - Eight Neighbour Laplacian":
-		kernel = EightNeighbourLaplacianKernel
-		divisor = 1
+// ConvolveKernel applies a kernel with floating-point weights. Every output
+// value is the weighted sum divided by divisor plus bias, rounded and
+// clamped to 0-255. nb selects the border mode and the anchor. Separable
+// kernels run as two 1-D passes and large kernels through an FFT.
+func ConvolveKernel(img image.Image, kernel *Kernel, divisor, bias float64, nb Neighborhood) (image.Image, error) {
+	if divisor == 0 {
+		return nil, &ParamError{Param: "divisor", Reason: "must not be zero"}
+	}
+	return convolve(context.Background(), img, kernel, divisor, bias, nb, MethodAuto)
+}
 
-	case "Convolution - Four Neighbour Laplacian Enhancement":
-		kernel = FourNeighbourLaplacianEnhancementKernel
-		divisor = 1
+// ConvolutionMethod 卷积的计算方式
+type ConvolutionMethod string
 
-	case "Convolution - Eight Neighbour Laplacian Enhancement":
-		kernel = EightNeighbourLaplacianEnhancementKernel
-		divisor = 1
+const (
+	MethodAuto      ConvolutionMethod = "auto"      // 按卷积核自动选择
+	MethodDirect    ConvolutionMethod = "direct"    // 逐像素遍历整个卷积核
+	MethodSeparable ConvolutionMethod = "separable" // 秩为 1 的卷积核分解为两次一维卷积
+	MethodFFT       ConvolutionMethod = "fft"       // 分块 FFT
+)
 
-	case "Convolution - Roberts One":
-		kernel = RobertsOneKernel
-		divisor = 1
+// ConvolutionMethods lists every convolution method.
+var ConvolutionMethods = []string{string(MethodAuto), string(MethodDirect), string(MethodSeparable), string(MethodFFT)}
 
-	case "Convolution - Roberts Two":
-		kernel = RobertsTwoKernel
-		divisor = 1
+// FFTKernelArea 不可分离的卷积核权重数达到该值时, MethodAuto 使用 FFT
+var FFTKernelArea = 49
 
-	case "Convolution - Sobel X":
-		kernel = SobelXKernel
-		divisor = 1
+// autoMethod 可分离的二维卷积核用两次一维卷积, 其余较大的卷积核用 FFT
+func autoMethod(kernel *Kernel, separable bool) ConvolutionMethod {
+	switch {
+	case separable && kernel.Width > 1 && kernel.Height > 1:
+		return MethodSeparable
+	case kernel.Width*kernel.Height >= FFTKernelArea:
+		return MethodFFT
+	}
+	return MethodDirect
+}
 
-	case "Convolution - Sobel Y":
-		kernel = SobelYKernel
-		divisor = 1
+func convolve(ctx context.Context, img image.Image, kernel *Kernel, divisor, bias float64, nb Neighborhood, method ConvolutionMethod) (image.Image, error) {
+	src, bounds, err := nb.pad(ctx, pixelsOf(img), kernel.Width, kernel.Height)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+	scale := 1 / divisor
 
+	col, row, separable := kernel.Separate()
+	if method == MethodAuto || method == "" {
+		method = autoMethod(kernel, separable)
+	}
+	switch method {
+	case MethodDirect:
+		err = convolveDirect(ctx, src, dst, kernel, scale, bias)
+	case MethodSeparable:
+		if !separable {
+			return nil, &ParamError{Param: "method", Reason: "kernel is not separable"}
+		}
+		err = convolveSeparable(ctx, src, dst, col, row, scale, bias)
+	case MethodFFT:
+		err = convolveFFT(ctx, src, dst, kernel, scale, bias)
 	default:
-		return "", errors.New("unsupported convolution algorithm")
+		return nil, &ParamError{Param: "method", Reason: fmt.Sprintf("unknown convolution method %q", method)}
+	}
+	if err != nil {
+		return nil, err
 	}
+	return dst.Image(), nil
+}
 
-	// 卷积核的尺寸
-	kernelSize := len(kernel)
-	offset := kernelSize / 2
-
-	// 遍历图像的每个像素
-	for y := offset; y < bounds.Dy()-offset; y++ {
-		for x := offset; x < bounds.Dx()-offset; x++ {
-			var rSum, gSum, bSum int
-
-			// 遍历卷积核
-			for ky := 0; ky < kernelSize; ky++ {
-				for kx := 0; kx < kernelSize; kx++ {
-					px := x + kx - offset
-					py := y + ky - offset
-					r, g, b, _ := img.At(px, py).RGBA()
-
-					rSum += int(r>>8) * kernel[ky][kx]
-					gSum += int(g>>8) * kernel[ky][kx]
-					bSum += int(b>>8) * kernel[ky][kx]
+// convolveDirect 输出像素 (x, y) 的窗口从 src 的 (x, y) 开始
+func convolveDirect(ctx context.Context, src, dst *Buffer, kernel *Kernel, scale, bias float64) error {
+	ch := src.Channels
+	n := min(ch, 3)
+
+	// 按行带并行遍历图像的每个像素
+	return parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		sums := make([]float64, n)
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				clear(sums)
+
+				// 遍历卷积核
+				for ky := 0; ky < kernel.Height; ky++ {
+					in := src.Row(y + ky)
+					weights := kernel.Weights[ky*kernel.Width : (ky+1)*kernel.Width]
+					for kx, weight := range weights {
+						i := (x + kx) * ch
+						for c := range sums {
+							sums[c] += float64(in[i+c]) * weight
+						}
+					}
 				}
+				storeConvolved(out[x*ch:(x+1)*ch], sums, scale, bias)
 			}
-
-			// 卷积后的新像素值
-			rSum /= divisor
-			gSum /= divisor
-			bSum /= divisor
-
-			// 确保值在0-255之间
-			rNew := uint8(clamp(rSum, 0, 255))
-			gNew := uint8(clamp(gSum, 0, 255))
-			bNew := uint8(clamp(bSum, 0, 255))
-
-			convolutionImage.Set(x, y, color.RGBA{rNew, gNew, bNew, 255})
 		}
-	}
+	})
+}
 
-	//processedImagePath := "static/uploads/convolution_result.jpg"
-	//out, err := os.Create(processedImagePath)
-	//if err != nil {
-	//	return "", err
-	//}
-	//defer out.Close()
-	//
-	//jpeg.Encode(out, convolutionImage, nil)
-	//return processedImagePath, nil
-	// 将结果图像写入内存中的 buffer
-	var buf bytes.Buffer
-	jpeg.Encode(&buf, convolutionImage, nil)
-	if err != nil {
-		return "", err
+// storeConvolved 写入卷积后的新像素值, 确保值在0-255之间, alpha 不透明
+func storeConvolved(px []uint8, sums []float64, scale, bias float64) {
+	for c, sum := range sums {
+		px[c] = clampFloat(float32(sum*scale + bias))
+	}
+	if len(px) == 4 {
+		px[3] = 255
 	}
-
-	// 将图像转换为 base64 编码
-	base64Image := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return base64Image, nil
 }
 
 // 各种卷积核定义
@@ -0,0 +1,133 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// testImages 随机内容的 RGBA 和灰度图像, 尺寸不是 2 的幂, 检查 FFT 分块的边缘
+func testImages() map[string]image.Image {
+	rng := rand.New(rand.NewSource(1))
+	r := image.Rect(0, 0, 37, 23)
+	rgba := image.NewRGBA(r)
+	gray := image.NewGray(r)
+	rng.Read(rgba.Pix)
+	rng.Read(gray.Pix)
+	// alpha 不参与卷积, 取不同的值确认它被原样保留
+	for i := 3; i < len(rgba.Pix); i += 4 {
+		rgba.Pix[i] = uint8(128 + i%128)
+	}
+	return map[string]image.Image{"rgba": rgba, "gray": gray}
+}
+
+// maxDiff 返回两张图像同一通道的最大差值, 大小不同时报错
+func maxDiff(t *testing.T, a, b image.Image) int {
+	t.Helper()
+	if a.Bounds() != b.Bounds() {
+		t.Fatalf("bounds differ: %v and %v", a.Bounds(), b.Bounds())
+	}
+	pa, pb := pixelsOf(a), pixelsOf(b)
+	if pa.Channels != pb.Channels {
+		t.Fatalf("channels differ: %d and %d", pa.Channels, pb.Channels)
+	}
+	diff := 0
+	for y := 0; y < pa.Height; y++ {
+		ra, rb := pa.Row(y), pb.Row(y)
+		for i := range ra {
+			diff = max(diff, abs(int(ra[i])-int(rb[i])))
+		}
+	}
+	return diff
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestConvolutionMethodsAgree(t *testing.T) {
+	kernels := []struct {
+		name      string
+		kernel    *Kernel
+		divisor   float64
+		bias      float64
+		separable bool
+	}{
+		{"gaussian", GaussianKernel(1.5, 0), 1, 0, true},
+		{"box 5x3", BoxKernel(5, 3), 15, 0, true},
+		{"sobel", IntKernel(SobelXKernel), 1, 128, true},
+		{"laplacian", IntKernel(EightNeighbourLaplacianKernel), 1, 128, false},
+		{"roberts 2x2", IntKernel(RobertsOneKernel), 1, 128, false},
+		{"random 9x7", randomKernel(9, 7), 1, 0, false},
+	}
+	for name, img := range testImages() {
+		for _, k := range kernels {
+			for _, border := range BorderModes {
+				nb := Neighborhood{Border: BorderMode(border), BorderValue: 200, AnchorX: -1, AnchorY: -1}
+				t.Run(fmt.Sprintf("%s/%s/%s", name, k.name, border), func(t *testing.T) {
+					want, err := convolve(context.Background(), img, k.kernel, k.divisor, k.bias, nb, MethodDirect)
+					if err != nil {
+						t.Fatal(err)
+					}
+					methods := []ConvolutionMethod{MethodFFT}
+					if k.separable {
+						methods = append(methods, MethodSeparable)
+					}
+					for _, method := range methods {
+						got, err := convolve(context.Background(), img, k.kernel, k.divisor, k.bias, nb, method)
+						if err != nil {
+							t.Fatalf("%s: %v", method, err)
+						}
+						if d := maxDiff(t, want, got); d > 1 {
+							t.Errorf("%s differs from %s by %d", method, MethodDirect, d)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// randomKernel 返回权重之和为 1 的随机卷积核, 包含负权重
+func randomKernel(width, height int) *Kernel {
+	rng := rand.New(rand.NewSource(2))
+	rows := make([][]float64, height)
+	sum := 0.0
+	for y := range rows {
+		rows[y] = make([]float64, width)
+		for x := range rows[y] {
+			rows[y][x] = rng.Float64() - 0.3
+			sum += rows[y][x]
+		}
+	}
+	for _, row := range rows {
+		for x := range row {
+			row[x] /= sum
+		}
+	}
+	k, err := NewKernel(rows)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func TestConvolveRejectsNonSeparable(t *testing.T) {
+	img := testImages()["gray"]
+	_, err := convolve(context.Background(), img, IntKernel(EightNeighbourLaplacianKernel), 1, 0, DefaultNeighborhood, MethodSeparable)
+	if _, ok := err.(*ParamError); !ok {
+		t.Fatalf("got %v, want a *ParamError", err)
+	}
+}
+
+func TestConvolveRejectsZeroDivisor(t *testing.T) {
+	_, err := Convolve(testImages()["gray"], AveragingKernel, 0)
+	if _, ok := err.(*ParamError); !ok {
+		t.Fatalf("got %v, want a *ParamError", err)
+	}
+}
@@ -0,0 +1,177 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Workers 是并行执行使用的 goroutine 数量, 0 表示使用 GOMAXPROCS
+var Workers = 0
+
+// 每个 worker 平均分到的行带数量, 大于 1 时可以平衡各行带耗时不同的情况
+const bandsPerWorker = 4
+
+func workerCount() int {
+	if Workers > 0 {
+		return Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelRows splits the rows [0, height) into bands and runs fn on them
+// across the worker goroutines. It stops handing out bands once ctx is
+// cancelled and reports progress after every band. A panic in fn stops the
+// other workers and is raised again on the calling goroutine, so that the
+// caller's recover still applies.
+func parallelRows(ctx context.Context, height int, fn func(y0, y1 int)) error {
+	workers := workerCount()
+	bands := workers * bandsPerWorker
+	if bands > height {
+		bands = height
+	}
+	if bands < 1 {
+		return ctx.Err()
+	}
+	bandHeight := (height + bands - 1) / bands
+	bands = (height + bandHeight - 1) / bandHeight
+	if workers > bands {
+		workers = bands
+	}
+
+	var next, done int64
+	var wg sync.WaitGroup
+	// 第一个 panic 的值, 其余 worker 看到 failed 后不再取新的行带
+	var failed atomic.Bool
+	var panicOnce sync.Once
+	var panicValue any
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panicOnce.Do(func() { panicValue = r })
+					failed.Store(true)
+				}
+			}()
+			for ctx.Err() == nil && !failed.Load() {
+				band := int(atomic.AddInt64(&next, 1)) - 1
+				if band >= bands {
+					return
+				}
+				y0 := band * bandHeight
+				y1 := y0 + bandHeight
+				if y1 > height {
+					y1 = height
+				}
+				fn(y0, y1)
+				reportProgress(ctx, float64(atomic.AddInt64(&done, 1))/float64(bands))
+			}
+		}()
+	}
+	wg.Wait()
+	if failed.Load() {
+		panic(panicValue)
+	}
+	return ctx.Err()
+}
+
+// pixelsOf 返回 img 的交错排列缓冲区. RGBA, NRGBA 和 Gray 图像不复制,
+// 其它类型转换为 RGBA
+func pixelsOf(img image.Image) *Buffer {
+	return BufferOf(img).Interleaved()
+}
+
+// colorChannels 返回像素中颜色通道的数量, alpha 不参与计算
+func colorChannels(px []uint8) int {
+	if len(px) > 3 {
+		return 3
+	}
+	return len(px)
+}
+
+// applyLUT 用查找表映射每个颜色通道, alpha 保持不变
+func applyLUT(ctx context.Context, img image.Image, lut *[256]uint8) (image.Image, error) {
+	src := pixelsOf(img)
+	dst := src.NewLike()
+
+	err := parallelRows(ctx, src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := src.Row(y), dst.Row(y)
+			if src.Channels == 1 {
+				for i, v := range in {
+					out[i] = lut[v]
+				}
+				continue
+			}
+			for i := 0; i < len(in); i += 4 {
+				out[i] = lut[in[i]]
+				out[i+1] = lut[in[i+1]]
+				out[i+2] = lut[in[i+2]]
+				out[i+3] = in[i+3]
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
+
+// mapPixels 对每个像素调用 fn. px 是结果图像中的像素, 调用前已复制原值;
+// RGBA 和 NRGBA 图像为 4 个字节, Gray 图像为 1 个字节
+func mapPixels(ctx context.Context, img image.Image, fn func(px []uint8)) (image.Image, error) {
+	src := pixelsOf(img)
+	dst := src.NewLike()
+	ch := src.Channels
+
+	err := parallelRows(ctx, src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			copy(out, src.Row(y))
+			for i := 0; i < len(out); i += ch {
+				fn(out[i : i+ch : i+ch])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
+
+// pixelFunc2 combines the pixels p1 and p2 of two images into dst. All three
+// have the same number of channels.
+type pixelFunc2 func(dst, p1, p2 []uint8)
+
+// combine applies fn to every pair of pixels of two images of the same size.
+// Images with the same color model are combined as they are, anything else
+// is combined as RGBA.
+func combine(ctx context.Context, img1, img2 image.Image, fn pixelFunc2) (image.Image, error) {
+	if img1.Bounds().Size() != img2.Bounds().Size() {
+		return nil, errImageSize
+	}
+
+	src1, src2 := pixelsOf(img1), pixelsOf(img2)
+	if src1.Model != src2.Model {
+		src1, src2 = BufferOf(toRGBA(img1)), BufferOf(toRGBA(img2))
+	}
+	dst := src1.NewLike()
+	ch := src1.Channels
+
+	err := parallelRows(ctx, src1.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in1, in2, out := src1.Row(y), src2.Row(y), dst.Row(y)
+			for i := 0; i < len(out); i += ch {
+				fn(out[i:i+ch:i+ch], in1[i:i+ch:i+ch], in2[i:i+ch:i+ch])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
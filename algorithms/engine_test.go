@@ -0,0 +1,86 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// 比较单 goroutine 与并行执行引擎的耗时:
+//
+//	go test -run '^$' -bench . ./algorithms
+
+// benchmarkImage 返回 seed 决定的随机不透明 RGBA 图像, 大小约 300 万像素
+func benchmarkImage(seed int64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	rand.New(rand.NewSource(seed)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// benchmarkWorkers 分别用 1 个和 GOMAXPROCS 个 worker 运行算子 name
+func benchmarkWorkers(b *testing.B, name string, images []image.Image, params Params) {
+	runs := []struct {
+		name    string
+		workers int
+	}{
+		{"workers=1", 1},
+		{"workers=GOMAXPROCS", runtime.GOMAXPROCS(0)},
+	}
+	for _, run := range runs {
+		b.Run(run.name, func(b *testing.B) {
+			saved := Workers
+			b.Cleanup(func() { Workers = saved })
+			Workers = run.workers
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Apply(name, images, params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPowerLaw(b *testing.B) {
+	benchmarkWorkers(b, "Power Law", []image.Image{benchmarkImage(1)}, Params{"c": 16.0, "gamma": 0.5})
+}
+
+func BenchmarkSaltPepperNoise(b *testing.B) {
+	benchmarkWorkers(b, "Salt&Pepper noise", []image.Image{benchmarkImage(1)}, Params{"seed": 1})
+}
+
+func BenchmarkAddition(b *testing.B) {
+	benchmarkWorkers(b, "Addition", []image.Image{benchmarkImage(1), benchmarkImage(2)}, nil)
+}
+
+func BenchmarkWeightedAveraging(b *testing.B) {
+	benchmarkWorkers(b, "Convolution - Weighted averaging", []image.Image{benchmarkImage(1)}, nil)
+}
+
+func BenchmarkSobelX(b *testing.B) {
+	benchmarkWorkers(b, "Convolution - Sobel X", []image.Image{benchmarkImage(1)}, nil)
+}
+
+func TestParallelRowsPanicReachesCaller(t *testing.T) {
+	saved := Workers
+	t.Cleanup(func() { Workers = saved })
+	Workers = 4
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("recovered %v, want boom", r)
+		}
+	}()
+	parallelRows(context.Background(), 100, func(y0, y1 int) {
+		if y0 <= 50 && 50 < y1 {
+			panic("boom")
+		}
+	})
+	t.Fatal("parallelRows returned normally")
+}
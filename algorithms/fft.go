@@ -0,0 +1,152 @@
+package algorithms
+
+import (
+	"context"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// fftPlan 固定长度 (2 的幂) 的基 2 FFT
+type fftPlan struct {
+	n       int
+	twiddle []complex128 // exp(-2πik/n), k < n/2
+	rev     []int        // 位反转后的下标
+}
+
+func newFFTPlan(n int) *fftPlan {
+	p := &fftPlan{n: n, twiddle: make([]complex128, n/2), rev: make([]int, n)}
+	for k := range p.twiddle {
+		p.twiddle[k] = cmplx.Rect(1, -2*math.Pi*float64(k)/float64(n))
+	}
+	shift := bits.UintSize - bits.Len(uint(n-1))
+	for i := range p.rev {
+		p.rev[i] = int(bits.Reverse(uint(i)) >> shift)
+	}
+	return p
+}
+
+// transform 原地计算一维 FFT, inverse 时不除以 n
+func (p *fftPlan) transform(a []complex128, inverse bool) {
+	for i, j := range p.rev {
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for size := 2; size <= p.n; size <<= 1 {
+		half, step := size/2, p.n/size
+		for start := 0; start < p.n; start += size {
+			for k := 0; k < half; k++ {
+				w := p.twiddle[k*step]
+				if inverse {
+					w = cmplx.Conj(w)
+				}
+				u, v := a[start+k], a[start+k+half]*w
+				a[start+k], a[start+k+half] = u+v, u-v
+			}
+		}
+	}
+}
+
+// transform2D 对 n x n 的矩阵先按行再按列做 FFT, column 是长度为 n 的临时空间
+func (p *fftPlan) transform2D(a, column []complex128, inverse bool) {
+	n := p.n
+	for y := 0; y < n; y++ {
+		p.transform(a[y*n:(y+1)*n], inverse)
+	}
+	for x := 0; x < n; x++ {
+		for y := range column {
+			column[y] = a[y*n+x]
+		}
+		p.transform(column, inverse)
+		for y, v := range column {
+			a[y*n+x] = v
+		}
+	}
+}
+
+// fftTileSize 分块 FFT 的边长, 使每块中有效输出占大部分
+func fftTileSize(kernel *Kernel) int {
+	size := 64
+	for size < 4*max(kernel.Width, kernel.Height) {
+		size <<= 1
+	}
+	return size
+}
+
+// convolveFFT 用重叠保留法分块计算卷积: 每块 size x size 的输入做 FFT,
+// 与卷积核频谱的共轭相乘后逆变换, 得到 (size-kw+1) x (size-kh+1) 个输出.
+// 卷积核是实数, 所以两个颜色通道可以作为实部和虚部放在同一次变换中
+func convolveFFT(ctx context.Context, src, dst *Buffer, kernel *Kernel, scale, bias float64) error {
+	size := fftTileSize(kernel)
+	plan := newFFTPlan(size)
+	stepX, stepY := size-kernel.Width+1, size-kernel.Height+1
+	ch := src.Channels
+	n := min(ch, 3)
+
+	spectrum := make([]complex128, size*size)
+	for y := 0; y < kernel.Height; y++ {
+		for x := 0; x < kernel.Width; x++ {
+			spectrum[y*size+x] = complex(kernel.At(x, y), 0)
+		}
+	}
+	plan.transform2D(spectrum, make([]complex128, size), false)
+	for i, v := range spectrum {
+		spectrum[i] = cmplx.Conj(v)
+	}
+	// 逆变换没有除以 size², 在缩放系数中补上
+	scale /= float64(size * size)
+
+	tilesX := (dst.Width + stepX - 1) / stepX
+	tilesY := (dst.Height + stepY - 1) / stepY
+	return parallelRows(ctx, tilesY, func(t0, t1 int) {
+		planes := make([][]complex128, (n+1)/2)
+		for i := range planes {
+			planes[i] = make([]complex128, size*size)
+		}
+		column := make([]complex128, size)
+		sums := make([]float64, n)
+
+		for ty := t0; ty < t1; ty++ {
+			oy := ty * stepY
+			for tx := 0; tx < tilesX; tx++ {
+				ox := tx * stepX
+				for pi, plane := range planes {
+					clear(plane)
+					for y := 0; y < size && oy+y < src.Height; y++ {
+						in := src.Row(oy + y)
+						for x := 0; x < size && ox+x < src.Width; x++ {
+							px := in[(ox+x)*ch:]
+							var im float64
+							if 2*pi+1 < n {
+								im = float64(px[2*pi+1])
+							}
+							plane[y*size+x] = complex(float64(px[2*pi]), im)
+						}
+					}
+					plan.transform2D(plane, column, false)
+					for i := range plane {
+						plane[i] *= spectrum[i]
+					}
+					plan.transform2D(plane, column, true)
+				}
+
+				for y := 0; y < stepY && oy+y < dst.Height; y++ {
+					out := dst.Row(oy + y)
+					for x := 0; x < stepX && ox+x < dst.Width; x++ {
+						for c := range sums {
+							v := planes[c/2][y*size+x]
+							if c%2 == 0 {
+								sums[c] = real(v)
+							} else {
+								sums[c] = imag(v)
+							}
+						}
+						i := (ox + x) * ch
+						storeConvolved(out[i:i+ch], sums, scale, bias)
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,423 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+// MaxOutputPixels 几何变换结果的最大像素数, 防止放大到无法分配的尺寸
+var MaxOutputPixels = 1 << 26
+
+// FlipDirection 翻转方向
+type FlipDirection string
+
+const (
+	FlipHorizontal FlipDirection = "horizontal" // 左右翻转
+	FlipVertical   FlipDirection = "vertical"   // 上下翻转
+	FlipBoth       FlipDirection = "both"       // 同时左右和上下翻转, 即旋转 180 度
+)
+
+// samplingBorderModes 采样可以使用的边界模式, crop 没有意义
+var samplingBorderModes = func() []string {
+	var modes []string
+	for _, m := range BorderModes {
+		if m != string(BorderCrop) {
+			modes = append(modes, m)
+		}
+	}
+	return modes
+}()
+
+func init() {
+	Register(Operation{
+		Name:     "Resize",
+		Label:    "缩放",
+		Category: CategoryGeometry,
+		Params: []ParamSpec{
+			{Name: "width", Type: ParamInt, Default: 0, Min: bound(0), Description: "目标宽度, 0 表示按高度保持宽高比"},
+			{Name: "height", Type: ParamInt, Default: 0, Min: bound(0), Description: "目标高度, 0 表示按宽度保持宽高比"},
+			{Name: "scale", Type: ParamFloat, Default: 0.0, Min: bound(0), Description: "宽和高都为 0 时使用的缩放比例"},
+			interpolationParam(),
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			b := a.Images[0].Bounds()
+			width, height, err := resizeSize(b.Dx(), b.Dy(), a.Params.Int("width"), a.Params.Int("height"), a.Params.Float("scale"))
+			if err != nil {
+				return nil, err
+			}
+			return resize(a.Ctx, a.Images[0], width, height, Interpolation(a.Params.String("interpolation")))
+		},
+	})
+	Register(Operation{
+		Name:     "Rotate",
+		Label:    "旋转",
+		Category: CategoryGeometry,
+		Params: []ParamSpec{
+			{Name: "angle", Type: ParamFloat, Required: true, Description: "逆时针旋转的角度"},
+			{Name: "expand", Type: ParamBool, Default: true, Description: "true 时扩大画布以容纳整幅图像, false 时保持原尺寸并裁掉超出的部分"},
+			interpolationParam(),
+			{Name: "border", Type: ParamEnum, Options: samplingBorderModes, Default: string(BorderConstant), Description: "原图以外区域的取值方式", Advanced: true},
+			{Name: "borderValue", Type: ParamInt, Default: 0, Min: bound(0), Max: bound(255), Description: "constant 模式的填充值", Advanced: true},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return rotate(a.Ctx, a.Images[0], a.Params.Float("angle"), a.Params.Bool("expand"), Interpolation(a.Params.String("interpolation")),
+				BorderMode(a.Params.String("border")), uint8(a.Params.Int("borderValue")))
+		},
+	})
+	Register(Operation{
+		Name:     "Flip",
+		Label:    "翻转",
+		Category: CategoryGeometry,
+		Params: []ParamSpec{
+			{Name: "direction", Type: ParamEnum, Options: []string{string(FlipHorizontal), string(FlipVertical), string(FlipBoth)}, Default: string(FlipHorizontal), Description: "翻转方向"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return flip(a.Ctx, a.Images[0], FlipDirection(a.Params.String("direction")))
+		},
+	})
+	Register(Operation{
+		Name:     "Transpose",
+		Label:    "转置",
+		Category: CategoryGeometry,
+		Apply: func(a *Args) (image.Image, error) {
+			return permute(a.Ctx, a.Images[0], true, false, false)
+		},
+	})
+	Register(Operation{
+		Name:     "Crop",
+		Label:    "裁剪",
+		Category: CategoryGeometry,
+		Params: []ParamSpec{
+			{Name: "x", Type: ParamInt, Default: 0, Min: bound(0), Description: "区域左上角的列"},
+			{Name: "y", Type: ParamInt, Default: 0, Min: bound(0), Description: "区域左上角的行"},
+			{Name: "width", Type: ParamInt, Required: true, Min: bound(1), Description: "区域宽度"},
+			{Name: "height", Type: ParamInt, Required: true, Min: bound(1), Description: "区域高度"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return crop(a.Ctx, a.Images[0], image.Rect(0, 0, a.Params.Int("width"), a.Params.Int("height")).Add(image.Pt(a.Params.Int("x"), a.Params.Int("y"))))
+		},
+	})
+}
+
+// resultOf 几何变换的结果: 输入为二值掩码时仍返回掩码
+func resultOf(img image.Image, dst *Buffer) image.Image {
+	if IsMask(img) {
+		return maskOf(dst)
+	}
+	return dst.Image()
+}
+
+// checkOutputSize 检查结果图像的尺寸
+func checkOutputSize(param string, width, height int) error {
+	if width < 1 || height < 1 {
+		return &ParamError{Param: param, Reason: fmt.Sprintf("the result would be %dx%d", width, height)}
+	}
+	if width > MaxOutputPixels/height {
+		return &ParamError{Param: param, Reason: fmt.Sprintf("the result would be %dx%d, more than %d pixels", width, height, MaxOutputPixels)}
+	}
+	return nil
+}
+
+// resizeSize 根据请求的宽、高或比例计算结果尺寸. 只给出宽或高时保持宽高比.
+// 推算出的尺寸先在浮点数中检查, 过大的值转换为 int 的结果不确定
+func resizeSize(srcWidth, srcHeight, width, height int, scale float64) (int, int, error) {
+	var w, h float64
+	param := "width"
+	switch {
+	case width > 0 && height > 0:
+		return width, height, nil
+	case width > 0:
+		w = float64(width)
+		h = max(math.Round(w*float64(srcHeight)/float64(srcWidth)), 1)
+	case height > 0:
+		param = "height"
+		h = float64(height)
+		w = max(math.Round(h*float64(srcWidth)/float64(srcHeight)), 1)
+	case math.IsNaN(scale) || math.IsInf(scale, 0):
+		return 0, 0, &ParamError{Param: "scale", Reason: "must be a finite number"}
+	case scale > 0:
+		param = "scale"
+		w = max(math.Round(float64(srcWidth)*scale), 1)
+		h = max(math.Round(float64(srcHeight)*scale), 1)
+	default:
+		return 0, 0, &ParamError{Param: "width", Reason: "one of width, height or scale is required"}
+	}
+	if w*h > float64(MaxOutputPixels) {
+		return 0, 0, &ParamError{Param: param, Reason: fmt.Sprintf("the result would be %.0fx%.0f, more than %d pixels", w, h, MaxOutputPixels)}
+	}
+	return int(w), int(h), nil
+}
+
+// Resize scales img to width x height. Bilinear, bicubic and Lanczos
+// interpolation widen their kernels when shrinking, so downscaled images
+// are smoothed instead of aliased. Binary masks stay masks.
+func Resize(img image.Image, width, height int, interp Interpolation) (image.Image, error) {
+	return resize(context.Background(), img, width, height, interp)
+}
+
+func resize(ctx context.Context, img image.Image, width, height int, interp Interpolation) (image.Image, error) {
+	if err := checkOutputSize("width", width, height); err != nil {
+		return nil, err
+	}
+	src := pixelsOf(img)
+	rect := image.Rect(0, 0, width, height)
+	ch := src.Channels
+
+	if interp == InterpNearest {
+		dst := NewBuffer(rect, ch, Interleaved, src.Model)
+		xs := make([]int, width)
+		for x := range xs {
+			xs[x] = min(int((float64(x)+0.5)*float64(src.Width)/float64(width)), src.Width-1)
+		}
+		err := parallelRows(ctx, height, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				sy := min(int((float64(y)+0.5)*float64(src.Height)/float64(height)), src.Height-1)
+				in, out := src.Row(sy), dst.Row(y)
+				for x, sx := range xs {
+					copy(out[x*ch:(x+1)*ch], in[sx*ch:])
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resultOf(img, dst), nil
+	}
+
+	f, err := filterOf(interp)
+	if err != nil {
+		return nil, err
+	}
+	cols, rows := contributions(src.Width, width, f), contributions(src.Height, height, f)
+
+	// 先水平缩放每一行, 再垂直缩放每一列
+	tmp := NewFloatBuffer(image.Rect(0, 0, width, src.Height), ch, Interleaved, src.Model)
+	err = parallelRows(subProgress(ctx, 0, 0.5), src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := src.Row(y), tmp.Row(y)
+			for x, con := range cols {
+				for c := 0; c < ch; c++ {
+					var sum float32
+					for i, w := range con.weights {
+						sum += w * float32(in[(con.first+i)*ch+c])
+					}
+					out[x*ch+c] = sum
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(rect, ch, Interleaved, src.Model)
+	err = parallelRows(subProgress(ctx, 0.5, 1), height, func(y0, y1 int) {
+		sums := make([]float32, width*ch)
+		for y := y0; y < y1; y++ {
+			con := rows[y]
+			clear(sums)
+			for i, w := range con.weights {
+				for x, v := range tmp.Row(con.first + i) {
+					sums[x] += w * v
+				}
+			}
+			for x, out := 0, dst.Row(y); x < len(out); x++ {
+				out[x] = clampFloat(sums[x])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultOf(img, dst), nil
+}
+
+// contribution 一个输出像素由输入中从 first 开始的连续像素加权得到
+type contribution struct {
+	first   int
+	weights []float32
+}
+
+// contributions 计算把 srcSize 个像素缩放为 dstSize 个时每个输出像素的权重.
+// 缩小时插值核按比例放宽, 相当于先低通滤波再采样. 超出边界的部分舍去后重新归一化
+func contributions(srcSize, dstSize int, f resampleFilter) []contribution {
+	scale := float64(srcSize) / float64(dstSize)
+	stretch := max(scale, 1)
+	support := f.support * stretch
+	cons := make([]contribution, dstSize)
+	for i := range cons {
+		center := (float64(i)+0.5)*scale - 0.5
+		lo := max(int(math.Floor(center-support))+1, 0)
+		hi := min(int(math.Ceil(center+support))-1, srcSize-1)
+		weights := make([]float32, hi-lo+1)
+		var sum float64
+		for j := lo; j <= hi; j++ {
+			w := f.fn((float64(j) - center) / stretch)
+			weights[j-lo] = float32(w)
+			sum += w
+		}
+		for j := range weights {
+			weights[j] /= float32(sum)
+		}
+		cons[i] = contribution{lo, weights}
+	}
+	return cons
+}
+
+// Rotate rotates img counterclockwise by degrees around its center. With
+// expand the canvas grows to hold the whole rotated image, otherwise it
+// keeps its size and the corners are cut off. Uncovered areas are black.
+// Multiples of 90 degrees move pixels without interpolation.
+func Rotate(img image.Image, degrees float64, expand bool, interp Interpolation) (image.Image, error) {
+	return rotate(context.Background(), img, degrees, expand, interp, BorderConstant, 0)
+}
+
+func rotate(ctx context.Context, img image.Image, degrees float64, expand bool, interp Interpolation, border BorderMode, value uint8) (image.Image, error) {
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return nil, &ParamError{Param: "angle", Reason: "must be a finite number"}
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// 90 度的整数倍直接移动像素
+	if q := degrees / 90; q == math.Trunc(q) {
+		switch mod(int(math.Mod(q, 4)), 4) {
+		case 0:
+			return permute(ctx, img, false, false, false)
+		case 2:
+			return permute(ctx, img, false, true, true)
+		case 1:
+			if expand || w == h {
+				return permute(ctx, img, true, true, false)
+			}
+		case 3:
+			if expand || w == h {
+				return permute(ctx, img, true, false, true)
+			}
+		}
+	}
+
+	rad := degrees * math.Pi / 180
+	sin, cos := math.Sincos(rad)
+	dw, dh := w, h
+	if expand {
+		// 去掉浮点误差, 避免多出一行或一列
+		dw = int(math.Ceil(math.Abs(float64(w)*cos) + math.Abs(float64(h)*sin) - 1e-6))
+		dh = int(math.Ceil(math.Abs(float64(w)*sin) + math.Abs(float64(h)*cos) - 1e-6))
+	}
+	if err := checkOutputSize("angle", dw, dh); err != nil {
+		return nil, err
+	}
+
+	src := pixelsOf(img)
+	s, err := newSampler(src, interp, border, value)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(image.Rect(0, 0, dw, dh), src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	// 结果中心对齐原图中心; y 轴向下, 逆时针旋转的逆变换为
+	// x = x'cos - y'sin, y = x'sin + y'cos
+	cx, cy := float64(w)/2, float64(h)/2
+	dcx, dcy := float64(dw)/2, float64(dh)/2
+	err = parallelRows(ctx, dh, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			ry := float64(y) + 0.5 - dcy
+			for x := 0; x < dw; x++ {
+				rx := float64(x) + 0.5 - dcx
+				sx := cx + rx*cos - ry*sin - 0.5
+				sy := cy + rx*sin + ry*cos - 0.5
+				s.at(sx, sy, out[x*ch:(x+1)*ch])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultOf(img, dst), nil
+}
+
+// Flip mirrors img horizontally, vertically or both.
+func Flip(img image.Image, direction FlipDirection) (image.Image, error) {
+	return flip(context.Background(), img, direction)
+}
+
+func flip(ctx context.Context, img image.Image, direction FlipDirection) (image.Image, error) {
+	switch direction {
+	case FlipHorizontal:
+		return permute(ctx, img, false, true, false)
+	case FlipVertical:
+		return permute(ctx, img, false, false, true)
+	case FlipBoth:
+		return permute(ctx, img, false, true, true)
+	}
+	return nil, &ParamError{Param: "direction", Reason: fmt.Sprintf("unknown flip direction %q", direction)}
+}
+
+// Transpose swaps the rows and columns of img, mirroring it along the main
+// diagonal.
+func Transpose(img image.Image) (image.Image, error) {
+	return permute(context.Background(), img, true, false, false)
+}
+
+// permute 只移动像素的变换: 结果 (x, y) 取原图 (x, y), transpose 时取 (y, x);
+// 之后 mirrorX, mirrorY 分别在原图中左右、上下翻转取值的位置.
+// 转置后左右翻转是逆时针旋转 90 度, 上下翻转是顺时针旋转 90 度
+func permute(ctx context.Context, img image.Image, transpose, mirrorX, mirrorY bool) (image.Image, error) {
+	src := pixelsOf(img)
+	dw, dh := src.Width, src.Height
+	if transpose {
+		dw, dh = dh, dw
+	}
+	dst := NewBuffer(image.Rect(0, 0, dw, dh), src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+
+	err := parallelRows(ctx, dh, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < dw; x++ {
+				sx, sy := x, y
+				if transpose {
+					sx, sy = y, x
+				}
+				if mirrorX {
+					sx = src.Width - 1 - sx
+				}
+				if mirrorY {
+					sy = src.Height - 1 - sy
+				}
+				copy(out[x*ch:(x+1)*ch], src.Row(sy)[sx*ch:])
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultOf(img, dst), nil
+}
+
+// Crop returns the part of img inside r, where r is relative to the top
+// left corner of img. The result starts at (0, 0).
+func Crop(img image.Image, r image.Rectangle) (image.Image, error) {
+	return crop(context.Background(), img, r)
+}
+
+func crop(ctx context.Context, img image.Image, r image.Rectangle) (image.Image, error) {
+	src := pixelsOf(img)
+	if r.Empty() || !r.In(image.Rect(0, 0, src.Width, src.Height)) {
+		return nil, &ParamError{Param: "width", Reason: fmt.Sprintf("the region %v is not inside the %dx%d image", r, src.Width, src.Height)}
+	}
+	dst := NewBuffer(image.Rect(0, 0, r.Dx(), r.Dy()), src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	err := parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			copy(dst.Row(y), src.Row(r.Min.Y + y)[r.Min.X*ch:])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultOf(img, dst), nil
+}
@@ -0,0 +1,46 @@
+package algorithms
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResizeSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		scale         float64
+		wantW, wantH  int
+	}{
+		{"both", 30, 10, 0, 30, 10},
+		{"width keeps aspect", 50, 0, 0, 50, 25},
+		{"height keeps aspect", 0, 10, 0, 20, 10},
+		{"scale", 0, 0, 0.5, 50, 25},
+		{"tiny scale keeps one pixel", 0, 0, 1e-9, 1, 1},
+	}
+	for _, tt := range tests {
+		w, h, err := resizeSize(100, 50, tt.width, tt.height, tt.scale)
+		if err != nil || w != tt.wantW || h != tt.wantH {
+			t.Errorf("%s: got %dx%d, %v, want %dx%d", tt.name, w, h, err, tt.wantW, tt.wantH)
+		}
+	}
+
+	bad := []struct {
+		name          string
+		width, height int
+		scale         float64
+	}{
+		{"nothing", 0, 0, 0},
+		{"infinite scale", 0, 0, math.Inf(1)},
+		{"NaN scale", 0, 0, math.NaN()},
+		{"huge scale", 0, 0, 1e12},
+		{"huge derived height", math.MaxInt, 0, 0},
+	}
+	for _, tt := range bad {
+		if _, _, err := resizeSize(100, 50, tt.width, tt.height, tt.scale); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		} else if _, ok := err.(*ParamError); !ok {
+			t.Errorf("%s: got %v, want a *ParamError", tt.name, err)
+		}
+	}
+}
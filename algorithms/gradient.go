@@ -0,0 +1,263 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+// GradientOperator 计算梯度使用的一对卷积核
+type GradientOperator string
+
+const (
+	OperatorSobel   GradientOperator = "sobel"
+	OperatorScharr  GradientOperator = "scharr"
+	OperatorPrewitt GradientOperator = "prewitt"
+	OperatorRoberts GradientOperator = "roberts"
+)
+
+// GradientOperators lists every gradient operator.
+var GradientOperators = []string{string(OperatorSobel), string(OperatorScharr), string(OperatorPrewitt), string(OperatorRoberts)}
+
+// GradientNorm 梯度幅值的计算方式
+type GradientNorm string
+
+const (
+	NormL1 GradientNorm = "l1" // |gx| + |gy|
+	NormL2 GradientNorm = "l2" // sqrt(gx² + gy²)
+)
+
+// GradientOptions selects how ComputeGradient differentiates the image.
+type GradientOptions struct {
+	Operator     GradientOperator
+	Norm         GradientNorm
+	Neighborhood Neighborhood
+}
+
+// DefaultGradientOptions uses the Sobel operator and the L2 norm.
+var DefaultGradientOptions = GradientOptions{Operator: OperatorSobel, Norm: NormL2, Neighborhood: DefaultNeighborhood}
+
+// Gradient is the signed derivative of an image's luminance.
+type Gradient struct {
+	// Magnitude 梯度幅值, 单通道
+	Magnitude *FloatBuffer
+	// Direction 梯度方向, 弧度, 范围 (-π, π]; 0 指向右, π/2 指向下
+	Direction *FloatBuffer
+}
+
+// 梯度算子的 x 和 y 方向卷积核
+var gradientKernels = map[GradientOperator][2][][]int{
+	OperatorSobel:   {SobelXKernel, SobelYKernel},
+	OperatorScharr:  {ScharrXKernel, ScharrYKernel},
+	OperatorPrewitt: {PrewittXKernel, PrewittYKernel},
+	// Roberts 的两个核沿对角线方向求导
+	OperatorRoberts: {RobertsOneKernel, RobertsTwoKernel},
+}
+
+// ScharrXKernel Scharr X 滤波器, 方向精度比 Sobel 高
+var ScharrXKernel = [][]int{
+	{-3, 0, 3},
+	{-10, 0, 10},
+	{-3, 0, 3},
+}
+
+// ScharrYKernel Scharr Y 滤波器
+var ScharrYKernel = [][]int{
+	{-3, -10, -3},
+	{0, 0, 0},
+	{3, 10, 3},
+}
+
+// PrewittXKernel Prewitt X 滤波器
+var PrewittXKernel = [][]int{
+	{-1, 0, 1},
+	{-1, 0, 1},
+	{-1, 0, 1},
+}
+
+// PrewittYKernel Prewitt Y 滤波器
+var PrewittYKernel = [][]int{
+	{-1, -1, -1},
+	{0, 0, 0},
+	{1, 1, 1},
+}
+
+func init() {
+	Register(Operation{
+		Name:     "Convolution - Gradient",
+		Label:    "卷积 - 梯度",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "operator", Type: ParamEnum, Options: GradientOperators, Default: string(OperatorSobel)},
+			{Name: "norm", Type: ParamEnum, Options: []string{string(NormL2), string(NormL1)}, Default: string(NormL2), Description: "l2 = sqrt(gx² + gy²), l1 = |gx| + |gy|"},
+			{Name: "output", Type: ParamEnum, Options: []string{"magnitude", "direction", "raw"}, Default: "magnitude", Description: "direction 用色相表示方向; raw 为幅值和弧度两个 float32 通道, 配合 outputFormat=float32 使用"},
+			{Name: "normalize", Type: ParamBool, Default: false, Description: "把最大幅值拉伸到 255"},
+		}, neighborhoodParams()...),
+		Apply: func(a *Args) (image.Image, error) {
+			opts := GradientOptions{
+				Operator:     GradientOperator(a.Params.String("operator")),
+				Norm:         GradientNorm(a.Params.String("norm")),
+				Neighborhood: neighborhoodOf(a.Params),
+			}
+			g, err := computeGradient(a.Ctx, a.Images[0], opts)
+			if err != nil {
+				return nil, err
+			}
+			switch a.Params.String("output") {
+			case "direction":
+				return g.DirectionImage(), nil
+			case "raw":
+				return g.Raw(), nil
+			}
+			return g.MagnitudeImage(a.Params.Bool("normalize")), nil
+		},
+	})
+}
+
+// ComputeGradient differentiates the luminance of img. Unlike the separate
+// Sobel X and Sobel Y convolutions, negative responses are kept.
+func ComputeGradient(img image.Image, opts GradientOptions) (*Gradient, error) {
+	return computeGradient(context.Background(), img, opts)
+}
+
+func computeGradient(ctx context.Context, img image.Image, opts GradientOptions) (*Gradient, error) {
+	kernels, ok := gradientKernels[opts.Operator]
+	if !ok {
+		return nil, &ParamError{Param: "operator", Reason: fmt.Sprintf("unknown gradient operator %q", opts.Operator)}
+	}
+	if opts.Norm != NormL1 && opts.Norm != NormL2 {
+		return nil, &ParamError{Param: "norm", Reason: fmt.Sprintf("unknown norm %q", opts.Norm)}
+	}
+	kx, ky := IntKernel(kernels[0]), IntKernel(kernels[1])
+
+	src, bounds, err := opts.Neighborhood.pad(ctx, LumaOf(img), kx.Width, kx.Height)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gradient{
+		Magnitude: NewFloatBuffer(bounds, 1, Interleaved, ModelGray),
+		Direction: NewFloatBuffer(bounds, 1, Interleaved, ModelGray),
+	}
+
+	err = parallelRows(ctx, g.Magnitude.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			magnitude, direction := g.Magnitude.Row(y), g.Direction.Row(y)
+			for x := range magnitude {
+				var gx, gy float64
+				for j := 0; j < kx.Height; j++ {
+					in := src.Row(y + j)
+					for i := 0; i < kx.Width; i++ {
+						v := float64(in[x+i])
+						gx += v * kx.At(i, j)
+						gy += v * ky.At(i, j)
+					}
+				}
+
+				// 幅值按算子本身的两个方向计算, 方向换算到 x/y 坐标
+				if opts.Norm == NormL1 {
+					magnitude[x] = float32(math.Abs(gx) + math.Abs(gy))
+				} else {
+					magnitude[x] = float32(math.Hypot(gx, gy))
+				}
+				dx, dy := gx, gy
+				if opts.Operator == OperatorRoberts {
+					// gx = p(x,y) - p(x+1,y+1), gy = p(x+1,y) - p(x,y+1)
+					dx, dy = (gy-gx)/2, -(gx+gy)/2
+				}
+				direction[x] = float32(math.Atan2(dy, dx))
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// MagnitudeImage returns the magnitude as a gray image, clamped to 0-255 or,
+// with normalize, scaled so that the strongest edge is 255.
+func (g *Gradient) MagnitudeImage(normalize bool) *image.Gray {
+	scale := float32(1)
+	if peak := g.maxMagnitude(); normalize && peak > 0 {
+		scale = 255 / peak
+	}
+	out := image.NewGray(g.Magnitude.Rect)
+	for y := 0; y < g.Magnitude.Height; y++ {
+		row := out.Pix[y*out.Stride:]
+		for x, v := range g.Magnitude.Row(y) {
+			row[x] = clampFloat(v * scale)
+		}
+	}
+	return out
+}
+
+// DirectionImage codes the direction as hue and the relative magnitude as
+// brightness, so flat areas are black.
+func (g *Gradient) DirectionImage() *image.RGBA {
+	peak := g.maxMagnitude()
+	out := image.NewRGBA(g.Magnitude.Rect)
+	for y := 0; y < g.Magnitude.Height; y++ {
+		row := out.Pix[y*out.Stride:]
+		magnitude, direction := g.Magnitude.Row(y), g.Direction.Row(y)
+		for x := range magnitude {
+			var value float64
+			if peak > 0 {
+				value = float64(magnitude[x] / peak)
+			}
+			hue := float64(direction[x]) * 180 / math.Pi
+			if hue < 0 {
+				hue += 360
+			}
+			px := row[x*4 : x*4+4]
+			px[0], px[1], px[2] = hsvToRGB(hue, 1, value)
+			px[3] = 255
+		}
+	}
+	return out
+}
+
+// Raw returns magnitude and direction as a two channel float image.
+func (g *Gradient) Raw() *FloatImage {
+	raw := NewFloatBuffer(g.Magnitude.Rect, 2, Interleaved, ModelGray)
+	for y := 0; y < raw.Height; y++ {
+		row := raw.Row(y)
+		magnitude, direction := g.Magnitude.Row(y), g.Direction.Row(y)
+		for x := range magnitude {
+			row[2*x], row[2*x+1] = magnitude[x], direction[x]
+		}
+	}
+	return &FloatImage{raw}
+}
+
+func (g *Gradient) maxMagnitude() float32 {
+	var peak float32
+	for _, v := range g.Magnitude.Pix {
+		peak = max(peak, v)
+	}
+	return peak
+}
+
+// hsvToRGB h 为 0-360 度, s 和 v 为 0-1
+func hsvToRGB(h, s, v float64) (uint8, uint8, uint8) {
+	c := v * s
+	hp := math.Mod(h/60, 6)
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return clampFloat(float32((r + m) * 255)), clampFloat(float32((g + m) * 255)), clampFloat(float32((b + m) * 255))
+}
@@ -0,0 +1,91 @@
+package algorithms
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// rampImage 灰度值为 10x + 20y 的灰度图像
+func rampImage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Pix[y*img.Stride+x] = uint8(10*x + 20*y)
+		}
+	}
+	return img
+}
+
+func TestGradientStepEdge(t *testing.T) {
+	// 台阶两侧各一列的 Sobel 响应为 (1+2+1)*255, 其余为 0; 只有 x 方向时 L1 和 L2 相同
+	for _, norm := range []GradientNorm{NormL1, NormL2} {
+		g, err := ComputeGradient(stepImage(8, 5), GradientOptions{Operator: OperatorSobel, Norm: norm, Neighborhood: Neighborhood{Border: BorderReplicate, AnchorX: -1, AnchorY: -1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 5; y++ {
+			magnitude, direction := g.Magnitude.Row(y), g.Direction.Row(y)
+			for x := range magnitude {
+				want := float32(0)
+				if x == 3 || x == 4 {
+					want = 1020
+				}
+				if magnitude[x] != want {
+					t.Fatalf("%s: magnitude (%d, %d) = %v, want %v", norm, x, y, magnitude[x], want)
+				}
+				if want > 0 && direction[x] != 0 {
+					t.Fatalf("%s: direction (%d, %d) = %v, want 0", norm, x, y, direction[x])
+				}
+			}
+		}
+
+		// 幅值超过 255 时截断, normalize 把最大值拉伸到 255, 两者在这里相同
+		for _, normalize := range []bool{false, true} {
+			img := g.MagnitudeImage(normalize)
+			if v := img.GrayAt(3, 2).Y; v != 255 {
+				t.Fatalf("%s: MagnitudeImage(%v) at the edge = %d, want 255", norm, normalize, v)
+			}
+			if v := img.GrayAt(0, 2).Y; v != 0 {
+				t.Fatalf("%s: MagnitudeImage(%v) in the flat area = %d, want 0", norm, normalize, v)
+			}
+		}
+	}
+}
+
+func TestGradientRamp(t *testing.T) {
+	// 斜率 (10, 20) 的 Sobel 响应为 gx = 4*2*10 = 80, gy = 4*2*20 = 160
+	want := map[GradientNorm]float64{NormL1: 240, NormL2: math.Hypot(80, 160)}
+	direction := math.Atan2(160, 80)
+	for norm, magnitude := range want {
+		g, err := ComputeGradient(rampImage(10, 8), GradientOptions{Operator: OperatorSobel, Norm: norm, Neighborhood: DefaultNeighborhood})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 只检查不受边界影响的内部像素
+		for y := 1; y < 7; y++ {
+			for x := 1; x < 9; x++ {
+				if v := float64(g.Magnitude.Row(y)[x]); math.Abs(v-magnitude) > 1e-3 {
+					t.Fatalf("%s: magnitude (%d, %d) = %v, want %v", norm, x, y, v, magnitude)
+				}
+				if v := float64(g.Direction.Row(y)[x]); math.Abs(v-direction) > 1e-6 {
+					t.Fatalf("%s: direction (%d, %d) = %v, want %v", norm, x, y, v, direction)
+				}
+			}
+		}
+	}
+}
+
+func TestGradientDirectionAgrees(t *testing.T) {
+	// 所有算子在渐变上给出相同的方向, Roberts 的对角方向换算到 x/y 坐标
+	want := math.Atan2(2, 1)
+	for _, operator := range GradientOperators {
+		g, err := ComputeGradient(rampImage(10, 8), GradientOptions{Operator: GradientOperator(operator), Norm: NormL2, Neighborhood: DefaultNeighborhood})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := float64(g.Direction.Row(4)[5]); math.Abs(v-want) > 1e-6 {
+			t.Errorf("%s: direction = %v, want %v", operator, v, want)
+		}
+	}
+}
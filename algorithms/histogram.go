@@ -0,0 +1,264 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// Histogram counts how often each 8-bit value occurs in one channel.
+type Histogram struct {
+	Counts [256]int
+	Total  int
+}
+
+// HistogramStats summarizes a histogram.
+type HistogramStats struct {
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+	Median int     `json:"median"`
+}
+
+// ImageHistogram holds the histograms of an image's color channels and of its
+// luminance. Red, Green and Blue are nil for gray images.
+type ImageHistogram struct {
+	Red       *Histogram
+	Green     *Histogram
+	Blue      *Histogram
+	Luminance *Histogram
+}
+
+// 直方图均衡化和匹配作用的通道
+const (
+	HistogramLuminance = "luminance" // 只处理亮度, 保持色调
+	HistogramChannels  = "channels"  // 每个颜色通道分别处理
+)
+
+func init() {
+	Register(Operation{
+		Name:     "Histogram Equalization",
+		Label:    "直方图均衡化",
+		Category: CategoryTransformation,
+		Params: []ParamSpec{
+			{Name: "mode", Type: ParamEnum, Options: []string{HistogramLuminance, HistogramChannels}, Default: HistogramLuminance, Description: "luminance 只均衡亮度, channels 分别均衡每个颜色通道"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return equalize(a.Ctx, a.Images[0], a.Params.String("mode"))
+		},
+	})
+	Register(Operation{
+		Name:     "Histogram Matching",
+		Label:    "直方图匹配",
+		Category: CategoryTransformation,
+		Arity:    2,
+		Params: []ParamSpec{
+			{Name: "mode", Type: ParamEnum, Options: []string{HistogramLuminance, HistogramChannels}, Default: HistogramLuminance, Description: "luminance 只匹配亮度, channels 分别匹配每个颜色通道"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return matchHistogram(a.Ctx, a.Images[0], a.Images[1], a.Params.String("mode"))
+		},
+	})
+}
+
+// ComputeHistogram counts the values of every color channel and of the
+// luminance of img.
+func ComputeHistogram(img image.Image) *ImageHistogram {
+	return computeHistogram(context.Background(), img)
+}
+
+func computeHistogram(ctx context.Context, img image.Image) *ImageHistogram {
+	src := pixelsOf(img)
+	gray := src.Channels == 1
+	var mu sync.Mutex
+	var result [4]Histogram // R, G, B, 亮度
+
+	parallelRows(ctx, src.Height, func(y0, y1 int) {
+		// 每个行带先在本地计数, 最后合并
+		var local [4][256]int
+		for y := y0; y < y1; y++ {
+			row := src.Row(y)
+			if gray {
+				for _, v := range row {
+					local[3][v]++
+				}
+				continue
+			}
+			for i := 0; i < len(row); i += src.Channels {
+				r, g, b := row[i], row[i+1], row[i+2]
+				local[0][r]++
+				local[1][g]++
+				local[2][b]++
+				local[3][luminance(r, g, b)]++
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for c := range local {
+			for v, n := range local[c] {
+				result[c].Counts[v] += n
+			}
+		}
+	})
+
+	for c := range result {
+		result[c].Total = src.Width * src.Height
+	}
+	h := &ImageHistogram{Luminance: &result[3]}
+	if !gray {
+		h.Red, h.Green, h.Blue = &result[0], &result[1], &result[2]
+	}
+	return h
+}
+
+// CDF returns the cumulative distribution, from 0 to 1.
+func (h *Histogram) CDF() []float64 {
+	cdf := make([]float64, len(h.Counts))
+	sum := 0
+	for v, n := range h.Counts {
+		sum += n
+		if h.Total > 0 {
+			cdf[v] = float64(sum) / float64(h.Total)
+		}
+	}
+	return cdf
+}
+
+// Stats returns the minimum, maximum, mean, standard deviation and median.
+func (h *Histogram) Stats() HistogramStats {
+	if h.Total == 0 {
+		return HistogramStats{}
+	}
+	stats := HistogramStats{Min: -1}
+	var sum, sumSquares float64
+	seen := 0
+	for v, n := range h.Counts {
+		if n == 0 {
+			continue
+		}
+		if stats.Min < 0 {
+			stats.Min = v
+		}
+		stats.Max = v
+		sum += float64(v * n)
+		sumSquares += float64(v * v * n)
+		if seen < (h.Total+1)/2 && seen+n >= (h.Total+1)/2 {
+			stats.Median = v
+		}
+		seen += n
+	}
+	stats.Mean = sum / float64(h.Total)
+	stats.StdDev = math.Sqrt(max(sumSquares/float64(h.Total)-stats.Mean*stats.Mean, 0))
+	return stats
+}
+
+// equalizationLUT 把累积分布线性拉伸到 0-255
+func (h *Histogram) equalizationLUT() *[256]uint8 {
+	var lut [256]uint8
+	first := 0
+	for first < len(h.Counts)-1 && h.Counts[first] == 0 {
+		first++
+	}
+	cdfMin, sum := h.Counts[first], 0
+	// 第一个非空灰度级之前的累积值小于 cdfMin, 这些灰度级不会出现, 保持为 0
+	for v := first; v < len(h.Counts); v++ {
+		sum += h.Counts[v]
+		if h.Total == cdfMin {
+			// 只有一种取值, 保持不变
+			lut[v] = uint8(v)
+			continue
+		}
+		lut[v] = uint8(math.Round(float64(sum-cdfMin) / float64(h.Total-cdfMin) * 255))
+	}
+	return &lut
+}
+
+// Equalize spreads the intensities of img over the full 0-255 range. mode is
+// HistogramLuminance or HistogramChannels.
+func Equalize(img image.Image, mode string) (image.Image, error) {
+	return equalize(context.Background(), img, mode)
+}
+
+func equalize(ctx context.Context, img image.Image, mode string) (image.Image, error) {
+	h := computeHistogram(ctx, img)
+	if h.Red == nil {
+		return applyLUT(ctx, img, h.Luminance.equalizationLUT())
+	}
+
+	switch mode {
+	case HistogramChannels:
+		return applyLUTs(ctx, img, [3]*[256]uint8{h.Red.equalizationLUT(), h.Green.equalizationLUT(), h.Blue.equalizationLUT()})
+	case HistogramLuminance, "":
+		return remapLuminance(ctx, img, h.Luminance.equalizationLUT())
+	}
+	return nil, &ParamError{Param: "mode", Reason: "must be " + HistogramLuminance + " or " + HistogramChannels}
+}
+
+// applyLUTs 每个颜色通道使用各自的查找表, alpha 保持不变
+func applyLUTs(ctx context.Context, img image.Image, luts [3]*[256]uint8) (image.Image, error) {
+	return mapPixels(ctx, img, func(px []uint8) {
+		for c := 0; c < colorChannels(px); c++ {
+			px[c] = luts[c][px[c]]
+		}
+	})
+}
+
+// remapLuminance 在 YCbCr 空间中用 lut 映射 Y, 色度保持不变
+func remapLuminance(ctx context.Context, img image.Image, lut *[256]uint8) (image.Image, error) {
+	return mapPixels(ctx, img, func(px []uint8) {
+		if len(px) == 1 {
+			px[0] = lut[px[0]]
+			return
+		}
+		y, cb, cr := color.RGBToYCbCr(px[0], px[1], px[2])
+		px[0], px[1], px[2] = color.YCbCrToRGB(lut[y], cb, cr)
+	})
+}
+
+// matchingLUT 把 h 的每个灰度级映射到 ref 中累积分布首次不小于它的灰度级
+func (h *Histogram) matchingLUT(ref *Histogram) *[256]uint8 {
+	var lut [256]uint8
+	cdf, refCDF := h.CDF(), ref.CDF()
+	u := 0
+	for v := range lut {
+		// 两个累积分布都单调递增, u 只需要向前移动
+		for u < 255 && refCDF[u] < cdf[v]-1e-12 {
+			u++
+		}
+		lut[v] = uint8(u)
+	}
+	return &lut
+}
+
+// MatchHistogram remaps img so that its intensity distribution matches that
+// of ref. The images may have different sizes. mode is HistogramLuminance or
+// HistogramChannels.
+func MatchHistogram(img, ref image.Image, mode string) (image.Image, error) {
+	return matchHistogram(context.Background(), img, ref, mode)
+}
+
+func matchHistogram(ctx context.Context, img, ref image.Image, mode string) (image.Image, error) {
+	h, r := computeHistogram(ctx, img), computeHistogram(ctx, ref)
+	if h.Red == nil {
+		return applyLUT(ctx, img, h.Luminance.matchingLUT(r.Luminance))
+	}
+
+	switch mode {
+	case HistogramChannels:
+		// 参考图像是灰度图像时, 每个通道都匹配它的亮度
+		refs := [3]*Histogram{r.Luminance, r.Luminance, r.Luminance}
+		if r.Red != nil {
+			refs = [3]*Histogram{r.Red, r.Green, r.Blue}
+		}
+		return applyLUTs(ctx, img, [3]*[256]uint8{
+			h.Red.matchingLUT(refs[0]), h.Green.matchingLUT(refs[1]), h.Blue.matchingLUT(refs[2]),
+		})
+	case HistogramLuminance, "":
+		return remapLuminance(ctx, img, h.Luminance.matchingLUT(r.Luminance))
+	}
+	return nil, &ParamError{Param: "mode", Reason: "must be " + HistogramLuminance + " or " + HistogramChannels}
+}
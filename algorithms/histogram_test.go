@@ -0,0 +1,81 @@
+package algorithms
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// grayOf 返回 1 行的灰度图像, 像素依次为 values
+func grayOf(values ...uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(values), 1))
+	copy(img.Pix, values)
+	return img
+}
+
+func TestEqualizationLUT(t *testing.T) {
+	// 100 出现 3 次, 150 出现 1 次, 200 出现 4 次
+	h := ComputeHistogram(grayOf(100, 100, 100, 150, 200, 200, 200, 200)).Luminance
+	lut := h.equalizationLUT()
+	for v, want := range map[int]uint8{0: 0, 99: 0, 100: 0, 120: 0, 150: 51, 199: 51, 200: 255, 255: 255} {
+		if lut[v] != want {
+			t.Errorf("lut[%d] = %d, want %d", v, lut[v], want)
+		}
+	}
+
+	// 只有一种取值时保持不变
+	lut = ComputeHistogram(grayOf(70, 70, 70)).Luminance.equalizationLUT()
+	if lut[70] != 70 {
+		t.Errorf("single value: lut[70] = %d, want 70", lut[70])
+	}
+}
+
+func TestEqualize(t *testing.T) {
+	got, err := Equalize(grayOf(100, 100, 100, 150, 200, 200, 200, 200), HistogramLuminance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{0, 0, 0, 51, 255, 255, 255, 255}; !bytes.Equal(pixelsOf(got).Pix, want) {
+		t.Fatalf("got %v, want %v", pixelsOf(got).Pix, want)
+	}
+}
+
+func TestMatchHistogram(t *testing.T) {
+	// 两个灰度级各占一半, 分别对应参考图像的两个灰度级; 参考图像的大小可以不同
+	img := grayOf(10, 20, 10, 20)
+	ref := grayOf(100, 100, 100, 200, 200, 200)
+	got, err := MatchHistogram(img, ref, HistogramLuminance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{100, 200, 100, 200}; !bytes.Equal(pixelsOf(got).Pix, want) {
+		t.Fatalf("got %v, want %v", pixelsOf(got).Pix, want)
+	}
+
+	// 匹配自身时出现的灰度级保持不变
+	self := testImages()["gray"]
+	got, err = MatchHistogram(self, self, HistogramLuminance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pixelsOf(got).Pix, pixelsOf(self).Pix) {
+		t.Fatal("matching an image to itself changes it")
+	}
+}
+
+func TestMatchHistogramChannels(t *testing.T) {
+	// 每个通道分别匹配灰度参考图像, alpha 不变
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(img.Pix, []uint8{10, 50, 90, 128, 20, 60, 80, 255})
+	got, err := MatchHistogram(img, grayOf(0, 255), HistogramChannels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint8{0, 0, 255, 128, 255, 255, 0, 255}; !bytes.Equal(pixelsOf(got).Pix, want) {
+		t.Fatalf("got %v, want %v", pixelsOf(got).Pix, want)
+	}
+
+	if _, err := MatchHistogram(img, img, "hue"); err == nil {
+		t.Fatal("accepted an unknown mode")
+	}
+}
@@ -0,0 +1,174 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+)
+
+// Interpolation 在非整数坐标处取像素值的方式
+type Interpolation string
+
+const (
+	InterpNearest  Interpolation = "nearest"
+	InterpBilinear Interpolation = "bilinear"
+	InterpBicubic  Interpolation = "bicubic" // Keys 三次卷积, a = -0.5
+	InterpLanczos  Interpolation = "lanczos" // Lanczos3
+)
+
+// Interpolations lists every supported interpolation.
+var Interpolations = []string{
+	string(InterpNearest), string(InterpBilinear), string(InterpBicubic), string(InterpLanczos),
+}
+
+// interpolationParam 所有几何算子共用的插值参数
+func interpolationParam() ParamSpec {
+	return ParamSpec{Name: "interpolation", Type: ParamEnum, Options: Interpolations, Default: string(InterpBilinear), Description: "非整数坐标处的取值方式"}
+}
+
+// resampleFilter 插值核: 距离采样点 d 的像素权重为 fn(d), |d| >= support 时为 0
+type resampleFilter struct {
+	support float64
+	fn      func(d float64) float64
+}
+
+// filterOf 返回插值方式对应的插值核, 最近邻没有插值核
+func filterOf(interp Interpolation) (resampleFilter, error) {
+	switch interp {
+	case InterpBilinear:
+		return resampleFilter{1, func(d float64) float64 { return 1 - math.Abs(d) }}, nil
+	case InterpBicubic:
+		return resampleFilter{2, cubic}, nil
+	case InterpLanczos:
+		return resampleFilter{3, lanczos3}, nil
+	}
+	return resampleFilter{}, &ParamError{Param: "interpolation", Reason: fmt.Sprintf("unknown interpolation %q", interp)}
+}
+
+func cubic(d float64) float64 {
+	const a = -0.5
+	d = math.Abs(d)
+	switch {
+	case d < 1:
+		return ((a+2)*d-(a+3))*d*d + 1
+	case d < 2:
+		return ((a*d-5*a)*d+8*a)*d - 4*a
+	}
+	return 0
+}
+
+func lanczos3(d float64) float64 {
+	d = math.Abs(d)
+	switch {
+	case d == 0:
+		return 1
+	case d < 3:
+		pd := math.Pi * d
+		return 3 * math.Sin(pd) * math.Sin(pd/3) / (pd * pd)
+	}
+	return 0
+}
+
+// sampler 在任意连续坐标处对缓冲区插值, 坐标以像素中心为整数.
+// 超出图像的位置按边界模式取值
+type sampler struct {
+	src    *Buffer
+	interp Interpolation
+	filter resampleFilter
+	border BorderMode
+	fill   []uint8
+}
+
+// maxTaps 每个方向最多的插值点数, Lanczos3 为 6
+const maxTaps = 6
+
+// newSampler 创建 src 的采样器. BorderConstant 使用 value 填充颜色通道, alpha 不透明,
+// 与邻域算子的填充相同
+func newSampler(src *Buffer, interp Interpolation, border BorderMode, value uint8) (*sampler, error) {
+	s := &sampler{src: src.Interleaved(), interp: interp, border: border}
+	if interp != InterpNearest {
+		f, err := filterOf(interp)
+		if err != nil {
+			return nil, err
+		}
+		s.filter = f
+	}
+	switch border {
+	case "":
+		s.border = BorderConstant
+	case BorderConstant, BorderReplicate, BorderReflect, BorderReflect101, BorderWrap:
+	default:
+		return nil, &ParamError{Param: "border", Reason: fmt.Sprintf("border mode %q can not be used for sampling", border)}
+	}
+	s.fill = make([]uint8, s.src.Channels)
+	for c := range s.fill {
+		s.fill[c] = value
+	}
+	if len(s.fill) == 4 {
+		s.fill[3] = 255
+	}
+	return s, nil
+}
+
+// pixel 返回整数坐标处的像素, 可以越界
+func (s *sampler) pixel(x, y int) []uint8 {
+	ch := s.src.Channels
+	sx, okx := s.border.index(x, s.src.Width)
+	sy, oky := s.border.index(y, s.src.Height)
+	if !okx || !oky {
+		return s.fill
+	}
+	return s.src.Row(sy)[sx*ch : (sx+1)*ch]
+}
+
+// at 把 (x, y) 处的插值写入 out
+func (s *sampler) at(x, y float64, out []uint8) {
+	if s.interp == InterpNearest {
+		copy(out, s.pixel(int(math.Floor(x+0.5)), int(math.Floor(y+0.5))))
+		return
+	}
+
+	var wx, wy [maxTaps]float64
+	x0, nx := s.weights(x, &wx)
+	y0, ny := s.weights(y, &wy)
+	var sums [4]float64
+	ch := s.src.Channels
+	inside := x0 >= 0 && y0 >= 0 && x0+nx <= s.src.Width && y0+ny <= s.src.Height
+	for j := 0; j < ny; j++ {
+		var row []uint8
+		if inside {
+			// 常见情况: 所有插值点都在图像内, 不必逐个处理边界
+			row = s.src.Row(y0 + j)[x0*ch:]
+		}
+		for i := 0; i < nx; i++ {
+			w := wx[i] * wy[j]
+			var p []uint8
+			if inside {
+				p = row[i*ch:]
+			} else {
+				p = s.pixel(x0+i, y0+j)
+			}
+			for c := 0; c < ch; c++ {
+				sums[c] += w * float64(p[c])
+			}
+		}
+	}
+	for c := 0; c < ch; c++ {
+		out[c] = clampFloat(float32(sums[c]))
+	}
+}
+
+// weights 计算坐标 v 附近各像素的归一化权重, 返回第一个像素的坐标和像素数
+func (s *sampler) weights(v float64, w *[maxTaps]float64) (int, int) {
+	first := int(math.Floor(v-s.filter.support)) + 1
+	n := 0
+	var sum float64
+	for i := first; float64(i) < v+s.filter.support && n < maxTaps; i++ {
+		w[n] = s.filter.fn(v - float64(i))
+		sum += w[n]
+		n++
+	}
+	for i := 0; i < n; i++ {
+		w[i] /= sum
+	}
+	return first, n
+}
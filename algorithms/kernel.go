@@ -0,0 +1,149 @@
+package algorithms
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxKernelSize 自定义卷积核每个方向的最大尺寸
+var MaxKernelSize = 31
+
+// MaxGaussianSigma 由 sigma 决定尺寸的高斯核允许的最大标准差. 这种核不受 MaxKernelSize
+// 限制, 边长为 2*ceil(3σ)+1, 按可分离卷积计算
+var MaxGaussianSigma = 100.0
+
+// Kernel is a convolution kernel of Width x Height floating-point weights,
+// stored row by row.
+type Kernel struct {
+	Width   int
+	Height  int
+	Weights []float64
+}
+
+// NewKernel builds a kernel from rows of weights. All rows must have the
+// same length, and neither side may exceed MaxKernelSize.
+func NewKernel(rows [][]float64) (*Kernel, error) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, fmt.Errorf("kernel is empty")
+	}
+	k := &Kernel{Width: len(rows[0]), Height: len(rows)}
+	if k.Width > MaxKernelSize || k.Height > MaxKernelSize {
+		return nil, fmt.Errorf("kernel is %dx%d, the maximum is %dx%d", k.Width, k.Height, MaxKernelSize, MaxKernelSize)
+	}
+	for i, row := range rows {
+		if len(row) != k.Width {
+			return nil, fmt.Errorf("kernel row %d has %d weights, expected %d", i, len(row), k.Width)
+		}
+		k.Weights = append(k.Weights, row...)
+	}
+	return k, nil
+}
+
+// IntKernel converts one of the predefined integer kernels.
+func IntKernel(rows [][]int) *Kernel {
+	k := &Kernel{Height: len(rows)}
+	for _, row := range rows {
+		k.Width = len(row)
+		for _, w := range row {
+			k.Weights = append(k.Weights, float64(w))
+		}
+	}
+	return k
+}
+
+// ParseKernel parses a kernel written as a JSON array of rows, for example
+// "[[1,2,1],[2,4,2],[1,2,1]]".
+func ParseKernel(s string) (*Kernel, error) {
+	var rows [][]float64
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &rows); err != nil {
+		return nil, fmt.Errorf("kernel must be a JSON array of rows of numbers")
+	}
+	return NewKernel(rows)
+}
+
+// At returns the weight in column x and row y.
+func (k *Kernel) At(x, y int) float64 {
+	return k.Weights[y*k.Width+x]
+}
+
+// Sum returns the sum of all weights.
+func (k *Kernel) Sum() float64 {
+	var sum float64
+	for _, w := range k.Weights {
+		sum += w
+	}
+	return sum
+}
+
+// Separate splits a rank-1 kernel into a column and a row vector so that
+// At(x, y) == col[y] * row[x]. ok is false when the kernel is not separable.
+func (k *Kernel) Separate() (col, row []float64, ok bool) {
+	// 以绝对值最大的权重为主元, 秩为 1 时每一行都是主元所在行的倍数
+	pivot := 0
+	for i, w := range k.Weights {
+		if math.Abs(w) > math.Abs(k.Weights[pivot]) {
+			pivot = i
+		}
+	}
+	p := k.Weights[pivot]
+	if p == 0 {
+		return nil, nil, false
+	}
+	px, py := pivot%k.Width, pivot/k.Width
+
+	col = make([]float64, k.Height)
+	row = make([]float64, k.Width)
+	for y := range col {
+		col[y] = k.At(px, y)
+	}
+	for x := range row {
+		row[x] = k.At(x, py) / p
+	}
+
+	tolerance := 1e-9 * math.Abs(p)
+	for y := range col {
+		for x := range row {
+			if math.Abs(col[y]*row[x]-k.At(x, y)) > tolerance {
+				return nil, nil, false
+			}
+		}
+	}
+	return col, row, true
+}
+
+// GaussianKernel returns a normalized size x size Gaussian kernel. A size of
+// 0 picks 2*ceil(3σ)+1, which covers all but 0.3% of the weight and is not
+// limited by MaxKernelSize; callers check sigma against MaxGaussianSigma.
+func GaussianKernel(sigma float64, size int) *Kernel {
+	if size <= 0 {
+		size = 2*int(math.Ceil(3*sigma)) + 1
+	}
+
+	// 一维高斯权重的外积
+	weights := make([]float64, size)
+	center := float64(size-1) / 2
+	var sum float64
+	for i := range weights {
+		d := float64(i) - center
+		weights[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += weights[i]
+	}
+	k := &Kernel{Width: size, Height: size, Weights: make([]float64, 0, size*size)}
+	for _, wy := range weights {
+		for _, wx := range weights {
+			k.Weights = append(k.Weights, wy*wx/(sum*sum))
+		}
+	}
+	return k
+}
+
+// BoxKernel returns a normalized width x height kernel of equal weights.
+func BoxKernel(width, height int) *Kernel {
+	k := &Kernel{Width: width, Height: height, Weights: make([]float64, width*height)}
+	for i := range k.Weights {
+		k.Weights[i] = 1 / float64(width*height)
+	}
+	return k
+}
@@ -0,0 +1,57 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"image/color"
+)
+
+// MaskPalette is the palette of binary masks: index 0 is the black
+// background and index 1 the white foreground.
+var MaskPalette = color.Palette{color.Gray{Y: 0}, color.Gray{Y: 255}}
+
+// NewMask returns an empty binary mask. Masks are paletted images with
+// MaskPalette, so PNG stores them with one bit per pixel. Pix holds 0 for
+// background and 1 for foreground pixels.
+func NewMask(r image.Rectangle) *image.Paletted {
+	return image.NewPaletted(r, MaskPalette)
+}
+
+// IsMask reports whether img is a binary mask with a black and a white
+// palette entry.
+func IsMask(img image.Image) bool {
+	p, ok := img.(*image.Paletted)
+	if !ok || len(p.Palette) != 2 {
+		return false
+	}
+	return color.GrayModel.Convert(p.Palette[0]).(color.Gray).Y == 0 &&
+		color.GrayModel.Convert(p.Palette[1]).(color.Gray).Y == 255
+}
+
+// maskBuffer 把掩码展开为 0/255 的灰度缓冲区
+func maskBuffer(mask *image.Paletted) *Buffer {
+	b := NewBuffer(mask.Rect, 1, Interleaved, ModelGray)
+	parallelRows(context.Background(), b.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in := mask.Pix[y*mask.Stride:]
+			for x, out := 0, b.Row(y); x < len(out); x++ {
+				out[x] = 255 * in[x]
+			}
+		}
+	})
+	return b
+}
+
+// maskOf 把单通道缓冲区转换为掩码, 不低于 128 的值为前景
+func maskOf(b *Buffer) *image.Paletted {
+	mask := NewMask(b.Rect)
+	parallelRows(context.Background(), b.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := mask.Pix[y*mask.Stride:]
+			for x, v := range b.Row(y) {
+				out[x] = v >> 7
+			}
+		}
+	})
+	return mask
+}
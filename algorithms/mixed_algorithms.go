@@ -1,127 +1,102 @@
 package algorithms
 
 import (
-	"bytes"
-	"encoding/base64"
+	"context"
 	"errors"
 	"image"
-	"image/color"
-	_ "image/gif"
-	"image/jpeg"
-	_ "image/png" // 必须导入以支持 PNG 解码
-	"math/rand"
-	"mime/multipart"
 )
 
-// MixedAlgorithms processes various mixed algorithms on a single image
-func MixedAlgorithms(file multipart.File, algorithm string, scalingFactor float64, shiftingValue float64, nBit int) (string, error) {
-	img, err := decodeImage(file)
-	if err != nil {
-		return "", err
-	}
-
-	bounds := img.Bounds()
-	resultImage := image.NewRGBA(bounds)
-
-	switch algorithm {
-	case "Negative":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				resultImage.Set(x, y, color.RGBA{
-					R: uint8(255 - (r >> 8)),
-					G: uint8(255 - (g >> 8)),
-					B: uint8(255 - (b >> 8)),
-					A: uint8(a >> 8),
-				})
-			}
-		}
+func init() {
+	Register(Operation{
+		Name:     "Negative",
+		Label:    "负片",
+		Category: CategoryMixed,
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], negativeLUT())
+		},
+	})
+	Register(Operation{
+		Name:     "Rescaling",
+		Label:    "重新缩放",
+		Category: CategoryMixed,
+		Params: []ParamSpec{
+			{Name: "scalingFactor", Type: ParamFloat, Required: true, Description: "multiplier applied to every channel"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], shiftRescaleLUT(a.Params.Float("scalingFactor"), 0))
+		},
+	})
+	Register(Operation{
+		Name:     "Shift&Rescale",
+		Label:    "移位和重新缩放",
+		Category: CategoryMixed,
+		Params: []ParamSpec{
+			{Name: "scalingFactor", Type: ParamFloat, Required: true, Description: "multiplier applied to every channel"},
+			{Name: "shiftingValue", Type: ParamFloat, Required: true, Description: "offset added after scaling"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], shiftRescaleLUT(a.Params.Float("scalingFactor"), a.Params.Float("shiftingValue")))
+		},
+	})
+	Register(Operation{
+		Name:     "Bit Plane Slicing",
+		Label:    "位平面切片",
+		Category: CategoryMixed,
+		Params: []ParamSpec{
+			{Name: "nBit", Type: ParamInt, Required: true, Min: bound(0), Max: bound(7), Description: "bit plane to extract, 0 is the least significant"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return bitPlane(a.Ctx, a.Images[0], a.Params.Int("nBit"))
+		},
+	})
+}
 
-	case "Rescaling":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				resultImage.Set(x, y, color.RGBA{
-					R: uint8(clamp(int(float64(r>>8)*scalingFactor), 0, 255)),
-					G: uint8(clamp(int(float64(g>>8)*scalingFactor), 0, 255)),
-					B: uint8(clamp(int(float64(b>>8)*scalingFactor), 0, 255)),
-					A: uint8(a >> 8),
-				})
-			}
-		}
+// Negative inverts the color channels of img.
+func Negative(img image.Image) image.Image {
+	result, _ := applyLUT(context.Background(), img, negativeLUT())
+	return result
+}
 
-	case "Shift&Rescale":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				resultImage.Set(x, y, color.RGBA{
-					R: uint8(clamp(int(float64(r>>8)*scalingFactor+shiftingValue), 0, 255)),
-					G: uint8(clamp(int(float64(g>>8)*scalingFactor+shiftingValue), 0, 255)),
-					B: uint8(clamp(int(float64(b>>8)*scalingFactor+shiftingValue), 0, 255)),
-					A: uint8(a >> 8),
-				})
-			}
-		}
+// Rescale multiplies every color channel by scalingFactor.
+func Rescale(img image.Image, scalingFactor float64) image.Image {
+	return ShiftRescale(img, scalingFactor, 0)
+}
 
-	case "Bit Plane Slicing":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				resultImage.Set(x, y, color.RGBA{
-					R: uint8(((r >> 8) >> nBit) & 1 * 255),
-					G: uint8(((g >> 8) >> nBit) & 1 * 255),
-					B: uint8(((b >> 8) >> nBit) & 1 * 255),
-					A: uint8(a >> 8),
-				})
-			}
-		}
+// ShiftRescale multiplies every color channel by scalingFactor and adds shiftingValue.
+func ShiftRescale(img image.Image, scalingFactor float64, shiftingValue float64) image.Image {
+	result, _ := applyLUT(context.Background(), img, shiftRescaleLUT(scalingFactor, shiftingValue))
+	return result
+}
 
-	case "Salt&Pepper noise":
-		// Implement Salt & Pepper noise addition
-		noiseProbability := 0.02 // Adjust as needed
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				if rand.Float64() < noiseProbability {
-					if rand.Intn(2) == 0 {
-						resultImage.Set(x, y, color.RGBA{0, 0, 0, uint8(a >> 8)}) // Salt
-					} else {
-						resultImage.Set(x, y, color.RGBA{255, 255, 255, uint8(a >> 8)}) // Pepper
-					}
-				} else {
-					resultImage.Set(x, y, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)})
-				}
-			}
-		}
+// BitPlane extracts bit plane nBit (0-7) of every color channel as a black and white image.
+func BitPlane(img image.Image, nBit int) (image.Image, error) {
+	return bitPlane(context.Background(), img, nBit)
+}
 
-	default:
-		return "", errors.New("unsupported algorithm")
+func negativeLUT() *[256]uint8 {
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(255 - i)
 	}
+	return &lut
+}
 
-	//processedImagePath := "static/uploads/mixed_algorithms_result.jpg"
-	//out, err := os.Create(processedImagePath)
-	//if err != nil {
-	//	return "", err
-	//}
-	//defer out.Close()
-
-	// 将结果图像写入内存中的 buffer
-	var buf bytes.Buffer
-	jpeg.Encode(&buf, resultImage, nil)
-	if err != nil {
-		return "", err
+func shiftRescaleLUT(scalingFactor float64, shiftingValue float64) *[256]uint8 {
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(clamp(int(float64(i)*scalingFactor+shiftingValue), 0, 255))
 	}
-
-	// 将图像转换为 base64 编码
-	base64Image := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return base64Image, nil
+	return &lut
 }
 
-// 解码图像
-func decodeImage(file multipart.File) (image.Image, error) {
-	img, _, err := image.Decode(file)
-	if err != nil {
-		return nil, err
+func bitPlane(ctx context.Context, img image.Image, nBit int) (image.Image, error) {
+	if nBit < 0 || nBit > 7 {
+		return nil, errors.New("bit plane must be between 0 and 7")
+	}
+
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8((i >> nBit) & 1 * 255)
 	}
-	return img, nil
+	return applyLUT(ctx, img, &lut)
 }
@@ -0,0 +1,422 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+// ElementShape 结构元素的形状
+type ElementShape string
+
+const (
+	ElementRect    ElementShape = "rect"
+	ElementCross   ElementShape = "cross"
+	ElementEllipse ElementShape = "ellipse"
+	// ElementCustom 由 kernel 参数给出, 非零的位置属于结构元素
+	ElementCustom ElementShape = "custom"
+)
+
+// ElementShapes lists the accepted element shapes.
+var ElementShapes = []string{string(ElementRect), string(ElementCross), string(ElementEllipse), string(ElementCustom)}
+
+// MorphologyOp 形态学运算
+type MorphologyOp string
+
+const (
+	MorphErode    MorphologyOp = "erode"
+	MorphDilate   MorphologyOp = "dilate"
+	MorphOpen     MorphologyOp = "open"     // 先腐蚀后膨胀, 去掉小的亮点
+	MorphClose    MorphologyOp = "close"    // 先膨胀后腐蚀, 填上小的暗洞
+	MorphTopHat   MorphologyOp = "tophat"   // 原图减去开运算, 保留小的亮细节
+	MorphBlackHat MorphologyOp = "blackhat" // 闭运算减去原图, 保留小的暗细节
+	MorphGradient MorphologyOp = "gradient" // 膨胀减去腐蚀, 得到轮廓
+)
+
+// StructuringElement is the neighborhood shape of the morphology
+// operations. Pixels where Mask is true take part in the minimum or
+// maximum; Mask is stored row by row.
+type StructuringElement struct {
+	Width  int
+	Height int
+	Mask   []bool
+}
+
+// MorphologyOptions configures Morphology.
+type MorphologyOptions struct {
+	Element *StructuringElement
+	// Iterations 腐蚀和膨胀各重复的次数
+	Iterations   int
+	Neighborhood Neighborhood
+}
+
+// DefaultMorphologyOptions uses a 3x3 rectangle once.
+var DefaultMorphologyOptions = MorphologyOptions{Element: RectElement(3, 3), Iterations: 1, Neighborhood: DefaultNeighborhood}
+
+func init() {
+	ops := []struct {
+		name  string
+		label string
+		op    MorphologyOp
+	}{
+		{"Morphology - Erode", "形态学 - 腐蚀", MorphErode},
+		{"Morphology - Dilate", "形态学 - 膨胀", MorphDilate},
+		{"Morphology - Open", "形态学 - 开运算", MorphOpen},
+		{"Morphology - Close", "形态学 - 闭运算", MorphClose},
+		{"Morphology - Top Hat", "形态学 - 顶帽", MorphTopHat},
+		{"Morphology - Black Hat", "形态学 - 黑帽", MorphBlackHat},
+		{"Morphology - Gradient", "形态学 - 梯度", MorphGradient},
+	}
+
+	params := append([]ParamSpec{
+		{Name: "element", Type: ParamEnum, Options: ElementShapes, Default: string(ElementRect), Description: "结构元素的形状"},
+		{Name: "width", Type: ParamInt, Default: 3, Min: bound(1), Description: "结构元素的宽度"},
+		{Name: "height", Type: ParamInt, Default: 3, Min: bound(1), Description: "结构元素的高度"},
+		{Name: "kernel", Type: ParamKernel, Description: "element=custom 时的结构元素, JSON 二维数组, 非零的位置属于结构元素"},
+		{Name: "iterations", Type: ParamInt, Default: 1, Min: bound(1), Max: bound(100), Description: "重复次数"},
+	}, neighborhoodParams()...)
+
+	for _, m := range ops {
+		op := m.op
+		Register(Operation{
+			Name:     m.name,
+			Label:    m.label,
+			Category: CategoryMorphology,
+			Params:   params,
+			Apply: func(a *Args) (image.Image, error) {
+				element, err := elementOf(a.Params)
+				if err != nil {
+					return nil, err
+				}
+				opts := MorphologyOptions{Element: element, Iterations: a.Params.Int("iterations"), Neighborhood: neighborhoodOf(a.Params)}
+				return morphology(a.Ctx, a.Images[0], op, opts)
+			},
+		})
+	}
+}
+
+// elementOf 从参数中读取结构元素
+func elementOf(p Params) (*StructuringElement, error) {
+	shape := ElementShape(p.String("element"))
+	kernel := p.Kernel("kernel")
+	if shape != ElementCustom {
+		if kernel != nil {
+			return nil, &ParamError{Param: "kernel", Reason: "only used with element=custom"}
+		}
+		element, err := NewStructuringElement(shape, p.Int("width"), p.Int("height"))
+		if err != nil {
+			return nil, &ParamError{Param: "element", Reason: err.Error()}
+		}
+		return element, nil
+	}
+
+	if kernel == nil {
+		return nil, &ParamError{Param: "kernel", Reason: "required for element=custom"}
+	}
+	element, err := ElementFromKernel(kernel)
+	if err != nil {
+		return nil, &ParamError{Param: "kernel", Reason: err.Error()}
+	}
+	return element, nil
+}
+
+// NewStructuringElement returns a width x height rectangle, cross or
+// ellipse. Neither side may exceed MaxKernelSize.
+func NewStructuringElement(shape ElementShape, width, height int) (*StructuringElement, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("element must be at least 1x1")
+	}
+	if width > MaxKernelSize || height > MaxKernelSize {
+		return nil, fmt.Errorf("element is %dx%d, the maximum is %dx%d", width, height, MaxKernelSize, MaxKernelSize)
+	}
+	switch shape {
+	case ElementRect, "":
+		return RectElement(width, height), nil
+	case ElementCross:
+		return CrossElement(width, height), nil
+	case ElementEllipse:
+		return EllipseElement(width, height), nil
+	}
+	return nil, fmt.Errorf("unknown element shape %q", shape)
+}
+
+// RectElement returns a width x height rectangle.
+func RectElement(width, height int) *StructuringElement {
+	se := &StructuringElement{Width: width, Height: height, Mask: make([]bool, width*height)}
+	for i := range se.Mask {
+		se.Mask[i] = true
+	}
+	return se
+}
+
+// CrossElement returns the middle row and the middle column of a width x
+// height rectangle.
+func CrossElement(width, height int) *StructuringElement {
+	se := &StructuringElement{Width: width, Height: height, Mask: make([]bool, width*height)}
+	cx, cy := (width-1)/2, (height-1)/2
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			se.Mask[y*width+x] = x == cx || y == cy
+		}
+	}
+	return se
+}
+
+// EllipseElement returns the ellipse inscribed in a width x height
+// rectangle, drawn the same way as OpenCV's MORPH_ELLIPSE.
+func EllipseElement(width, height int) *StructuringElement {
+	se := &StructuringElement{Width: width, Height: height, Mask: make([]bool, width*height)}
+	rx, ry := width/2, height/2
+	for y := 0; y < height; y++ {
+		// 每一行覆盖 [cx-dx, cx+dx]
+		dx := rx
+		if dy := y - ry; ry > 0 {
+			dx = int(math.Round(float64(rx) * math.Sqrt(float64(ry*ry-dy*dy)/float64(ry*ry))))
+		}
+		for x := max(rx-dx, 0); x < min(rx+dx+1, width); x++ {
+			se.Mask[y*width+x] = true
+		}
+	}
+	return se
+}
+
+// ElementFromKernel uses the non-zero weights of k as the element.
+func ElementFromKernel(k *Kernel) (*StructuringElement, error) {
+	se := &StructuringElement{Width: k.Width, Height: k.Height, Mask: make([]bool, len(k.Weights))}
+	empty := true
+	for i, w := range k.Weights {
+		se.Mask[i] = w != 0
+		empty = empty && w == 0
+	}
+	if empty {
+		return nil, fmt.Errorf("element has no non-zero weights")
+	}
+	return se, nil
+}
+
+// isRect 所有位置都属于结构元素时可以分两个方向计算
+func (se *StructuringElement) isRect() bool {
+	for _, in := range se.Mask {
+		if !in {
+			return false
+		}
+	}
+	return true
+}
+
+// Morphology applies a morphological operation to every color channel of
+// img. Binary masks (see NewMask) stay masks; for other images the result
+// has the color model of img.
+func Morphology(img image.Image, op MorphologyOp, opts MorphologyOptions) (image.Image, error) {
+	return morphology(context.Background(), img, op, opts)
+}
+
+func morphology(ctx context.Context, img image.Image, op MorphologyOp, opts MorphologyOptions) (image.Image, error) {
+	if opts.Element == nil {
+		opts.Element = DefaultMorphologyOptions.Element
+	}
+	opts.Iterations = max(opts.Iterations, 1)
+	src := pixelsOf(img)
+
+	var out *Buffer
+	var err error
+	switch op {
+	case MorphErode:
+		out, err = morphSequence(ctx, src, opts, false)
+	case MorphDilate:
+		out, err = morphSequence(ctx, src, opts, true)
+	case MorphOpen:
+		out, err = morphSequence(ctx, src, opts, false, true)
+	case MorphClose:
+		out, err = morphSequence(ctx, src, opts, true, false)
+	case MorphTopHat:
+		out, err = morphSequence(ctx, src, opts, false, true)
+		if err == nil {
+			out = subtractBuffers(src, out)
+		}
+	case MorphBlackHat:
+		out, err = morphSequence(ctx, src, opts, true, false)
+		if err == nil {
+			out = subtractBuffers(out, src)
+		}
+	case MorphGradient:
+		var eroded, dilated *Buffer
+		eroded, err = morphSequence(subProgress(ctx, 0, 0.5), src, opts, false)
+		if err == nil {
+			dilated, err = morphSequence(subProgress(ctx, 0.5, 1), src, opts, true)
+		}
+		if err == nil {
+			out = subtractBuffers(dilated, eroded)
+		}
+	default:
+		return nil, &ParamError{Param: "operation", Reason: fmt.Sprintf("unknown morphology operation %q", op)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if IsMask(img) {
+		return maskOf(out), nil
+	}
+	return out.Image(), nil
+}
+
+// morphSequence 依次执行腐蚀 (false) 或膨胀 (true), 每一步重复 opts.Iterations 次
+func morphSequence(ctx context.Context, src *Buffer, opts MorphologyOptions, steps ...bool) (*Buffer, error) {
+	total := float64(len(steps) * opts.Iterations)
+	done := 0
+	for _, dilate := range steps {
+		for i := 0; i < opts.Iterations; i++ {
+			stepCtx := subProgress(ctx, float64(done)/total, float64(done+1)/total)
+			var err error
+			src, err = morphStep(stepCtx, src, opts.Element, dilate, opts.Neighborhood)
+			if err != nil {
+				return nil, err
+			}
+			done++
+		}
+	}
+	return src, nil
+}
+
+// morphStep 每个颜色通道取结构元素覆盖范围内的最小值 (腐蚀) 或最大值 (膨胀),
+// alpha 取锚点处的值
+func morphStep(ctx context.Context, src *Buffer, se *StructuringElement, dilate bool, nb Neighborhood) (*Buffer, error) {
+	ax, ay, err := nb.anchor(se.Width, se.Height)
+	if err != nil {
+		return nil, err
+	}
+	padded, bounds, err := nb.pad(ctx, src, se.Width, se.Height)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+
+	if se.isRect() {
+		err = morphRect(ctx, padded, dst, se, dilate)
+	} else {
+		err = morphDirect(ctx, padded, dst, se, dilate)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ch := src.Channels; ch == 4 {
+		for y := 0; y < dst.Height; y++ {
+			in, out := padded.Row(y + ay)[ax*ch:], dst.Row(y)
+			for i := 3; i < len(out); i += ch {
+				out[i] = in[i]
+			}
+		}
+	}
+	return dst, nil
+}
+
+// morphDirect 遍历结构元素中的每个位置
+func morphDirect(ctx context.Context, src, dst *Buffer, se *StructuringElement, dilate bool) error {
+	ch := src.Channels
+	n := min(ch, 3)
+
+	// 结构元素中的位置相对于窗口左上角的偏移
+	var offsets []int
+	for y := 0; y < se.Height; y++ {
+		for x := 0; x < se.Width; x++ {
+			if se.Mask[y*se.Width+x] {
+				offsets = append(offsets, y*src.Stride+x*ch)
+			}
+		}
+	}
+
+	return parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				start := y*src.Stride + x*ch
+				for c := 0; c < n; c++ {
+					v := src.Pix[start+offsets[0]+c]
+					for _, o := range offsets[1:] {
+						if p := src.Pix[start+o+c]; dilate {
+							v = max(v, p)
+						} else {
+							v = min(v, p)
+						}
+					}
+					out[x*ch+c] = v
+				}
+			}
+		}
+	})
+}
+
+// morphRect 矩形结构元素先在水平方向取极值, 再在垂直方向取极值
+func morphRect(ctx context.Context, src, dst *Buffer, se *StructuringElement, dilate bool) error {
+	ch := src.Channels
+	n := min(ch, 3)
+	pick := func(a, b uint8) uint8 {
+		if dilate {
+			return max(a, b)
+		}
+		return min(a, b)
+	}
+
+	// 水平方向的中间结果, 宽度与输出相同, 高度与扩展后的输入相同
+	tmp := NewBuffer(image.Rect(0, 0, dst.Width, src.Height), n, Interleaved, src.Model)
+	err := parallelRows(subProgress(ctx, 0, 0.5), src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := src.Row(y), tmp.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				for c := 0; c < n; c++ {
+					v := in[x*ch+c]
+					for kx := 1; kx < se.Width; kx++ {
+						v = pick(v, in[(x+kx)*ch+c])
+					}
+					out[x*n+c] = v
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return parallelRows(subProgress(ctx, 0.5, 1), dst.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				for c := 0; c < n; c++ {
+					v := tmp.Pix[y*tmp.Stride+x*n+c]
+					for ky := 1; ky < se.Height; ky++ {
+						v = pick(v, tmp.Pix[(y+ky)*tmp.Stride+x*n+c])
+					}
+					out[x*ch+c] = v
+				}
+			}
+		}
+	})
+}
+
+// subtractBuffers 计算 a - b, 小于 0 的值截断为 0. 两者大小不同时 (边界为 crop)
+// 只保留重叠的部分, alpha 取自 a
+func subtractBuffers(a, b *Buffer) *Buffer {
+	rect := a.Rect.Intersect(b.Rect)
+	dst := NewBuffer(rect, a.Channels, Interleaved, a.Model)
+	ch := a.Channels
+	n := min(ch, 3)
+	parallelRows(context.Background(), dst.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			pa := a.Row(y + rect.Min.Y - a.Rect.Min.Y)[(rect.Min.X-a.Rect.Min.X)*ch:]
+			pb := b.Row(y + rect.Min.Y - b.Rect.Min.Y)[(rect.Min.X-b.Rect.Min.X)*ch:]
+			out := dst.Row(y)
+			for i := 0; i < len(out); i += ch {
+				for c := 0; c < n; c++ {
+					out[i+c] = pa[i+c] - min(pa[i+c], pb[i+c])
+				}
+				if ch == 4 {
+					out[i+3] = pa[i+3]
+				}
+			}
+		}
+	})
+	return dst
+}
@@ -0,0 +1,141 @@
+package algorithms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"testing"
+)
+
+// morph 执行一次形态学运算, 出错时结束测试
+func morph(t *testing.T, img image.Image, op MorphologyOp, opts MorphologyOptions) image.Image {
+	t.Helper()
+	out, err := Morphology(img, op, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestMorphologyComposition(t *testing.T) {
+	elements := map[string]*StructuringElement{
+		"rect":    RectElement(3, 5),
+		"cross":   CrossElement(5, 5),
+		"ellipse": EllipseElement(7, 5),
+	}
+	for name, img := range testImages() {
+		for shape, se := range elements {
+			for _, iterations := range []int{1, 2} {
+				opts := MorphologyOptions{Element: se, Iterations: iterations, Neighborhood: DefaultNeighborhood}
+				t.Run(fmt.Sprintf("%s/%s/%d", name, shape, iterations), func(t *testing.T) {
+					eroded, dilated := morph(t, img, MorphErode, opts), morph(t, img, MorphDilate, opts)
+					open, closed := morph(t, img, MorphOpen, opts), morph(t, img, MorphClose, opts)
+
+					// 开运算是先腐蚀后膨胀, 闭运算是先膨胀后腐蚀
+					if maxDiff(t, morph(t, eroded, MorphDilate, opts), open) != 0 {
+						t.Error("erode then dilate differs from open")
+					}
+					if maxDiff(t, morph(t, dilated, MorphErode, opts), closed) != 0 {
+						t.Error("dilate then erode differs from close")
+					}
+
+					// 顶帽, 黑帽和形态学梯度都是两个结果之差
+					diffs := []struct {
+						op   MorphologyOp
+						a, b image.Image
+					}{
+						{MorphTopHat, img, open},
+						{MorphBlackHat, closed, img},
+						{MorphGradient, dilated, eroded},
+					}
+					for _, d := range diffs {
+						got, a, b := pixelsOf(morph(t, img, d.op, opts)), pixelsOf(d.a), pixelsOf(d.b)
+						for i := range got.Pix {
+							want := a.Pix[i] - min(a.Pix[i], b.Pix[i])
+							if i%4 == 3 && got.Channels == 4 {
+								want = a.Pix[i]
+							}
+							if got.Pix[i] != want {
+								t.Fatalf("%s: byte %d = %d, want %d", d.op, i, got.Pix[i], want)
+							}
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestMorphologyRectMatchesDirect(t *testing.T) {
+	// 矩形结构元素分两个方向计算, 结果与逐个位置比较相同
+	se := RectElement(4, 3)
+	for name, img := range testImages() {
+		src := pixelsOf(img)
+		padded, bounds, err := DefaultNeighborhood.pad(context.Background(), src, se.Width, se.Height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, dilate := range []bool{false, true} {
+			rect := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+			direct := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+			if err := morphRect(context.Background(), padded, rect, se, dilate); err != nil {
+				t.Fatal(err)
+			}
+			if err := morphDirect(context.Background(), padded, direct, se, dilate); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(rect.Pix, direct.Pix) {
+				t.Errorf("%s: dilate=%v: separable result differs", name, dilate)
+			}
+		}
+	}
+}
+
+func TestMorphologyMask(t *testing.T) {
+	// 孤立的前景点和 3x3 的前景方块
+	mask := NewMask(image.Rect(0, 0, 9, 7))
+	mask.SetColorIndex(1, 1, 1)
+	for y := 2; y < 5; y++ {
+		for x := 4; x < 7; x++ {
+			mask.SetColorIndex(x, y, 1)
+		}
+	}
+	opts := MorphologyOptions{Element: RectElement(3, 3), Iterations: 1, Neighborhood: DefaultNeighborhood}
+
+	// 开运算去掉孤立点, 保留方块
+	open := morph(t, mask, MorphOpen, opts)
+	assertMask(t, open, func(i int) bool {
+		x, y := i%9, i/9
+		return x >= 4 && x < 7 && y >= 2 && y < 5
+	})
+
+	// 十字膨胀把孤立点变成十字, 方块的四角不向外扩展
+	opts.Element = CrossElement(3, 3)
+	dilated := morph(t, mask, MorphDilate, opts)
+	assertMask(t, dilated, func(i int) bool {
+		x, y := i%9, i/9
+		point := abs(x-1)+abs(y-1) <= 1
+		square := (x >= 3 && x < 8 && y >= 2 && y < 5) || (x >= 4 && x < 7 && y >= 1 && y < 6)
+		return point || square
+	})
+}
+
+func TestEllipseElement(t *testing.T) {
+	// 与 OpenCV 的 getStructuringElement(MORPH_ELLIPSE, (5, 5)) 相同
+	want := []string{
+		"..#..",
+		"#####",
+		"#####",
+		"#####",
+		"..#..",
+	}
+	se := EllipseElement(5, 5)
+	for y, row := range want {
+		for x, c := range row {
+			if se.Mask[y*5+x] != (c == '#') {
+				t.Fatalf("(%d, %d) = %v, want %c", x, y, se.Mask[y*5+x], c)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+func init() {
+	Register(Operation{
+		Name:     "Non-Local Means Denoising",
+		Label:    "非局部均值去噪",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "h", Type: ParamFloat, Default: 10.0, Min: bound(0.01), Description: "滤波强度, 越大去噪越多、细节越少"},
+			{Name: "patchSize", Type: ParamInt, Default: 7, Min: bound(1), Description: "比较相似度的块的边长, 奇数"},
+			{Name: "searchSize", Type: ParamInt, Default: 21, Min: bound(1), Description: "搜索相似块的窗口边长, 奇数"},
+		}, neighborhoodParams()[:2]...), // 窗口总是居中, 没有锚点参数
+		Apply: func(a *Args) (image.Image, error) {
+			return nonLocalMeans(a.Ctx, a.Images[0], a.Params.Float("h"), a.Params.Int("patchSize"), a.Params.Int("searchSize"), neighborhoodOf(a.Params))
+		},
+	})
+}
+
+// NonLocalMeans denoises img by replacing every pixel with a weighted mean
+// of the pixels in its searchSize x searchSize window. The weight of a
+// pixel depends on how similar the patchSize x patchSize patch around it is
+// to the patch around the center, so repeated texture is averaged while
+// edges are kept. h controls the strength.
+func NonLocalMeans(img image.Image, h float64, patchSize, searchSize int, nb Neighborhood) (image.Image, error) {
+	return nonLocalMeans(context.Background(), img, h, patchSize, searchSize, nb)
+}
+
+func nonLocalMeans(ctx context.Context, img image.Image, h float64, patchSize, searchSize int, nb Neighborhood) (image.Image, error) {
+	if h <= 0 {
+		return nil, &ParamError{Param: "h", Reason: "must be positive"}
+	}
+	for _, p := range []struct {
+		name string
+		size int
+	}{{"patchSize", patchSize}, {"searchSize", searchSize}} {
+		if p.size < 1 || p.size%2 == 0 {
+			return nil, &ParamError{Param: p.name, Reason: "must be a positive odd number"}
+		}
+		if p.size > MaxKernelSize {
+			return nil, &ParamError{Param: p.name, Reason: fmt.Sprintf("must be at most %d", MaxKernelSize)}
+		}
+	}
+
+	pr, sr := patchSize/2, searchSize/2
+	m := pr + sr // 输出像素到扩展后窗口边缘的距离
+	nb.AnchorX, nb.AnchorY = -1, -1
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(ctx, src, 2*m+1, 2*m+1)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	n := min(ch, 3)
+	w := dst.Width
+
+	// 块内平均的差的平方 (取整) 对应的权重
+	weights := make([]float32, 255*255+1)
+	for d2 := range weights {
+		weights[d2] = float32(math.Exp(-float64(d2) / (h * h)))
+	}
+	scale := 1 / float32(patchSize*patchSize*n)
+
+	// 对搜索窗口中的每个位移 (dx, dy), 先求整幅图像逐像素的差的平方, 再用滑动的
+	// 行和列求和得到每个块的距离, 每个像素的计算量与块的大小无关
+	err = parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		rows := y1 - y0
+		dw, dh := w+2*pr, rows+2*pr
+		diff := make([]int32, dw*dh)
+		cols := make([]int32, dw)
+		total := make([]float32, rows*w)
+		sums := make([]float32, rows*w*n)
+
+		for dy := -sr; dy <= sr; dy++ {
+			for dx := -sr; dx <= sr; dx++ {
+				// diff 的第 yy 行、第 xx 列是以输出像素 (xx-pr, y0+yy-pr) 为中心的差
+				for yy := 0; yy < dh; yy++ {
+					a := padded.Row(y0 + yy + sr)[sr*ch:]
+					b := padded.Row(y0 + yy + sr + dy)[(sr+dx)*ch:]
+					line := diff[yy*dw : (yy+1)*dw]
+					for xx := range line {
+						var s int32
+						for c := 0; c < n; c++ {
+							d := int32(a[xx*ch+c]) - int32(b[xx*ch+c])
+							s += d * d
+						}
+						line[xx] = s
+					}
+				}
+
+				clear(cols)
+				for yy := 0; yy < 2*pr; yy++ {
+					for xx, v := range diff[yy*dw : (yy+1)*dw] {
+						cols[xx] += v
+					}
+				}
+				for y := 0; y < rows; y++ {
+					for xx, v := range diff[(y+2*pr)*dw : (y+2*pr+1)*dw] {
+						cols[xx] += v
+					}
+					var s int32
+					for xx := 0; xx < 2*pr; xx++ {
+						s += cols[xx]
+					}
+					q := padded.Row(y0 + y + m + dy)[(m+dx)*ch:]
+					for x := 0; x < w; x++ {
+						s += cols[x+2*pr]
+						weight := weights[min(int(float32(s)*scale), len(weights)-1)]
+						total[y*w+x] += weight
+						for c := 0; c < n; c++ {
+							sums[(y*w+x)*n+c] += weight * float32(q[x*ch+c])
+						}
+						s -= cols[x]
+					}
+					for xx, v := range diff[y*dw : (y+1)*dw] {
+						cols[xx] -= v
+					}
+				}
+			}
+		}
+
+		for y := 0; y < rows; y++ {
+			out := dst.Row(y0 + y)
+			center := padded.Row(y0 + y + m)[m*ch:]
+			for x := 0; x < w; x++ {
+				for c := 0; c < n; c++ {
+					out[x*ch+c] = clampFloat(sums[(y*w+x)*n+c] / total[y*w+x])
+				}
+				if ch == 4 {
+					out[x*ch+3] = center[x*ch+3]
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
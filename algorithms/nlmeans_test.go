@@ -0,0 +1,94 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"testing"
+)
+
+// naiveNonLocalMeans 对每个像素逐个比较搜索窗口中的块, 作为滑动求和实现的参照
+func naiveNonLocalMeans(t *testing.T, img image.Image, h float64, patchSize, searchSize int, nb Neighborhood) []uint8 {
+	t.Helper()
+	pr, sr := patchSize/2, searchSize/2
+	m := pr + sr
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(context.Background(), src, 2*m+1, 2*m+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := src.Channels
+	n := min(ch, 3)
+	at := func(x, y, c int) int { return int(padded.Row(y + m)[(x+m)*ch+c]) }
+
+	var out []uint8
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			var sums [3]float64
+			var total float64
+			for dy := -sr; dy <= sr; dy++ {
+				for dx := -sr; dx <= sr; dx++ {
+					d2 := 0
+					for py := -pr; py <= pr; py++ {
+						for px := -pr; px <= pr; px++ {
+							for c := 0; c < n; c++ {
+								d := at(x+px, y+py, c) - at(x+dx+px, y+dy+py, c)
+								d2 += d * d
+							}
+						}
+					}
+					// 与实现相同, 块内平均取整后查权重
+					mean := min(d2/(patchSize*patchSize*n), 255*255)
+					w := math.Exp(-float64(mean) / (h * h))
+					total += w
+					for c := 0; c < n; c++ {
+						sums[c] += w * float64(at(x+dx, y+dy, c))
+					}
+				}
+			}
+			for c := 0; c < n; c++ {
+				out = append(out, uint8(math.Round(sums[c]/total)))
+			}
+			if ch == 4 {
+				out = append(out, uint8(at(x, y, 3)))
+			}
+		}
+	}
+	return out
+}
+
+func TestNonLocalMeans(t *testing.T) {
+	sizes := [][2]int{{1, 3}, {3, 5}, {5, 7}}
+	for name, img := range testImages() {
+		for _, s := range sizes {
+			t.Run(fmt.Sprintf("%s/%dx%d", name, s[0], s[1]), func(t *testing.T) {
+				got, err := NonLocalMeans(img, 30, s[0], s[1], DefaultNeighborhood)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := naiveNonLocalMeans(t, img, 30, s[0], s[1], DefaultNeighborhood)
+				for i, v := range pixelsOf(got).Pix {
+					if abs(int(v)-int(want[i])) > 1 {
+						t.Fatalf("byte %d = %d, want %d", i, v, want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNonLocalMeansKeepsEdges(t *testing.T) {
+	img := stepImage(20, 12)
+	got, err := NonLocalMeans(img, 10, 3, 7, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxDiff(t, got, img); d != 0 {
+		t.Fatalf("step edge changed by %d", d)
+	}
+
+	if _, err := NonLocalMeans(img, 10, 4, 7, DefaultNeighborhood); err == nil {
+		t.Fatal("accepted an even patch size")
+	}
+}
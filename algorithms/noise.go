@@ -0,0 +1,229 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/rand/v2"
+)
+
+// NoiseKind 噪声类型
+type NoiseKind string
+
+const (
+	NoiseSaltPepper NoiseKind = "salt-pepper"
+	NoiseGaussian   NoiseKind = "gaussian"
+	NoiseSpeckle    NoiseKind = "speckle"
+	NoisePoisson    NoiseKind = "poisson"
+	NoiseUniform    NoiseKind = "uniform"
+)
+
+// NoiseOptions configures AddNoise. Only the fields of the chosen kind are
+// used; zero values are used as they are, so start from DefaultNoise to get
+// the defaults of a kind.
+type NoiseOptions struct {
+	Kind NoiseKind
+	// Density 椒盐噪声替换的像素比例, SaltRatio 其中白点 (盐) 的比例
+	Density   float64
+	SaltRatio float64
+	// Mean, Sigma 高斯噪声的均值和标准差; 斑点噪声只使用 Sigma, 是相对于像素值的比例
+	Mean  float64
+	Sigma float64
+	// Scale 泊松噪声中每个灰度级对应的光子数, 越大噪声越小
+	Scale float64
+	// Low, High 均匀噪声的范围
+	Low  float64
+	High float64
+	// Seed 随机数种子, 相同的种子和输入总是得到相同的结果
+	Seed int64
+}
+
+// DefaultNoise returns the default options of the given kind, with Kind set.
+// Sigma depends on the kind: 20 gray levels for Gaussian noise, but 0.1 of
+// the pixel value for speckle noise.
+func DefaultNoise(kind NoiseKind) NoiseOptions {
+	opts := NoiseOptions{Kind: kind, Density: 0.02, SaltRatio: 0.5, Sigma: 20, Scale: 1, Low: -20, High: 20}
+	if kind == NoiseSpeckle {
+		// 斑点噪声的 Sigma 是相对于像素值的比例, 20 会把图像变成黑白
+		opts.Sigma = 0.1
+	}
+	return opts
+}
+
+// seedParam 所有随机算子共用的可选参数
+func seedParam() ParamSpec {
+	return ParamSpec{Name: "seed", Type: ParamInt, Min: bound(0), Description: "随机数种子, 不填时随机选择并在元数据中返回"}
+}
+
+// seedOf 返回 seed 参数, 没有指定时随机选择. 使用的种子记录在元数据中, 以便复现结果
+func seedOf(ctx context.Context, p Params) int64 {
+	seed := int64(p.Int("seed"))
+	if !p.Has("seed") {
+		// 不超过 2^53, 在 JSON 和 JavaScript 中不丢失精度
+		seed = rand.Int64N(1 << 53)
+	}
+	reportMetadata(ctx, "seed", seed)
+	return seed
+}
+
+func init() {
+	noises := []struct {
+		name   string
+		label  string
+		kind   NoiseKind
+		params []ParamSpec
+	}{
+		{"Salt&Pepper noise", "椒盐噪声", NoiseSaltPepper, []ParamSpec{
+			{Name: "density", Type: ParamFloat, Default: DefaultNoise(NoiseSaltPepper).Density, Min: bound(0), Max: bound(1), Description: "被替换为黑点或白点的像素比例"},
+			{Name: "saltRatio", Type: ParamFloat, Default: DefaultNoise(NoiseSaltPepper).SaltRatio, Min: bound(0), Max: bound(1), Description: "噪声中白点 (盐) 的比例"},
+		}},
+		{"Gaussian noise", "高斯噪声", NoiseGaussian, []ParamSpec{
+			{Name: "mean", Type: ParamFloat, Default: DefaultNoise(NoiseGaussian).Mean, Description: "噪声的均值"},
+			{Name: "sigma", Type: ParamFloat, Default: DefaultNoise(NoiseGaussian).Sigma, Min: bound(0), Description: "噪声的标准差"},
+		}},
+		{"Speckle noise", "斑点噪声", NoiseSpeckle, []ParamSpec{
+			{Name: "sigma", Type: ParamFloat, Default: DefaultNoise(NoiseSpeckle).Sigma, Min: bound(0), Description: "乘性噪声的标准差, v + v * n"},
+		}},
+		{"Poisson noise", "泊松噪声", NoisePoisson, []ParamSpec{
+			{Name: "scale", Type: ParamFloat, Default: DefaultNoise(NoisePoisson).Scale, Min: bound(0.001), Description: "每个灰度级对应的光子数, 越大噪声越小"},
+		}},
+		{"Uniform noise", "均匀噪声", NoiseUniform, []ParamSpec{
+			{Name: "low", Type: ParamFloat, Default: DefaultNoise(NoiseUniform).Low, Description: "加到像素值上的最小值"},
+			{Name: "high", Type: ParamFloat, Default: DefaultNoise(NoiseUniform).High, Description: "加到像素值上的最大值"},
+		}},
+	}
+
+	for _, n := range noises {
+		kind := n.kind
+		Register(Operation{
+			Name:     n.name,
+			Label:    n.label,
+			Category: CategoryMixed,
+			Params:   append(n.params, seedParam()),
+			Apply: func(a *Args) (image.Image, error) {
+				opts := NoiseOptions{
+					Kind:      kind,
+					Density:   a.Params.Float("density"),
+					SaltRatio: a.Params.Float("saltRatio"),
+					Mean:      a.Params.Float("mean"),
+					Sigma:     a.Params.Float("sigma"),
+					Scale:     a.Params.Float("scale"),
+					Low:       a.Params.Float("low"),
+					High:      a.Params.Float("high"),
+					Seed:      seedOf(a.Ctx, a.Params),
+				}
+				return addNoise(a.Ctx, a.Images[0], opts)
+			},
+		})
+	}
+}
+
+// SaltPepper replaces 2% of the pixels with black or white, using a random
+// seed.
+func SaltPepper(img image.Image) image.Image {
+	opts := DefaultNoise(NoiseSaltPepper)
+	opts.Seed = rand.Int64()
+	result, _ := addNoise(context.Background(), img, opts)
+	return result
+}
+
+// AddNoise adds noise of the given kind to every color channel of img.
+// Salt and pepper noise sets all color channels of a pixel at once. The
+// result depends only on img and opts, not on the number of workers.
+func AddNoise(img image.Image, opts NoiseOptions) (image.Image, error) {
+	return addNoise(context.Background(), img, opts)
+}
+
+func addNoise(ctx context.Context, img image.Image, opts NoiseOptions) (image.Image, error) {
+	var fn func(px []uint8, rng *rand.Rand)
+	switch opts.Kind {
+	case NoiseSaltPepper:
+		fn = func(px []uint8, rng *rand.Rand) {
+			if rng.Float64() >= opts.Density {
+				return
+			}
+			var v uint8 // 胡椒, 黑点
+			if rng.Float64() < opts.SaltRatio {
+				v = 255 // 盐, 白点
+			}
+			for c := 0; c < colorChannels(px); c++ {
+				px[c] = v
+			}
+		}
+	case NoiseGaussian:
+		fn = func(px []uint8, rng *rand.Rand) {
+			for c := 0; c < colorChannels(px); c++ {
+				px[c] = clampFloat(float32(float64(px[c]) + opts.Mean + opts.Sigma*rng.NormFloat64()))
+			}
+		}
+	case NoiseSpeckle:
+		fn = func(px []uint8, rng *rand.Rand) {
+			for c := 0; c < colorChannels(px); c++ {
+				v := float64(px[c])
+				px[c] = clampFloat(float32(v + v*opts.Sigma*rng.NormFloat64()))
+			}
+		}
+	case NoisePoisson:
+		if opts.Scale <= 0 {
+			return nil, &ParamError{Param: "scale", Reason: "must be positive"}
+		}
+		fn = func(px []uint8, rng *rand.Rand) {
+			for c := 0; c < colorChannels(px); c++ {
+				px[c] = clampFloat(float32(poisson(rng, float64(px[c])*opts.Scale) / opts.Scale))
+			}
+		}
+	case NoiseUniform:
+		if opts.Low > opts.High {
+			return nil, &ParamError{Param: "low", Reason: "must not be above high"}
+		}
+		fn = func(px []uint8, rng *rand.Rand) {
+			for c := 0; c < colorChannels(px); c++ {
+				px[c] = clampFloat(float32(float64(px[c]) + opts.Low + (opts.High-opts.Low)*rng.Float64()))
+			}
+		}
+	default:
+		return nil, &ParamError{Param: "kind", Reason: fmt.Sprintf("unknown noise kind %q", opts.Kind)}
+	}
+	return mapPixelsRand(ctx, img, opts.Seed, fn)
+}
+
+// mapPixelsRand 与 mapPixels 相同, 但每一行使用由 seed 和行号确定的随机数生成器,
+// 因此结果与行带的划分无关
+func mapPixelsRand(ctx context.Context, img image.Image, seed int64, fn func(px []uint8, rng *rand.Rand)) (image.Image, error) {
+	src := pixelsOf(img)
+	dst := src.NewLike()
+	ch := src.Channels
+
+	err := parallelRows(ctx, src.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			rng := rand.New(rand.NewPCG(uint64(seed), uint64(y)))
+			out := dst.Row(y)
+			copy(out, src.Row(y))
+			for i := 0; i < len(out); i += ch {
+				fn(out[i:i+ch:i+ch], rng)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
+
+// poisson 返回均值为 lambda 的泊松分布随机数. lambda 较大时用正态分布近似
+func poisson(rng *rand.Rand, lambda float64) float64 {
+	if lambda <= 0 {
+		return 0
+	}
+	if lambda > 30 {
+		return max(math.Round(lambda+math.Sqrt(lambda)*rng.NormFloat64()), 0)
+	}
+	// Knuth: 乘以均匀随机数直到小于 e^-lambda
+	limit, p, k := math.Exp(-lambda), 1.0, -1.0
+	for p > limit {
+		p *= rng.Float64()
+		k++
+	}
+	return k
+}
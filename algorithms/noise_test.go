@@ -0,0 +1,66 @@
+package algorithms
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+var noiseKinds = []NoiseKind{NoiseSaltPepper, NoiseGaussian, NoiseSpeckle, NoisePoisson, NoiseUniform}
+
+// addNoiseWorkers 用 workers 个 worker 添加噪声, 返回交错排列的像素
+func addNoiseWorkers(t *testing.T, img image.Image, opts NoiseOptions, workers int) []uint8 {
+	t.Helper()
+	saved := Workers
+	t.Cleanup(func() { Workers = saved })
+	Workers = workers
+
+	result, err := AddNoise(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pixelsOf(result).Pix
+}
+
+func TestAddNoiseIndependentOfWorkers(t *testing.T) {
+	for name, img := range testImages() {
+		for _, kind := range noiseKinds {
+			t.Run(name+"/"+string(kind), func(t *testing.T) {
+				opts := DefaultNoise(kind)
+				opts.Seed = 42
+				single := addNoiseWorkers(t, img, opts, 1)
+				parallel := addNoiseWorkers(t, img, opts, 8)
+				if !bytes.Equal(single, parallel) {
+					t.Fatal("1 worker and 8 workers give different results")
+				}
+				if bytes.Equal(single, pixelsOf(img).Pix) {
+					t.Fatal("no noise was added")
+				}
+
+				opts.Seed = 43
+				if bytes.Equal(single, addNoiseWorkers(t, img, opts, 1)) {
+					t.Fatal("seeds 42 and 43 give the same result")
+				}
+			})
+		}
+	}
+}
+
+func TestDefaultSpeckleKeepsImage(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	opts := DefaultNoise(NoiseSpeckle)
+	opts.Seed = 1
+	noisy := addNoiseWorkers(t, img, opts, 0)
+
+	// sigma 为 0.1 时平均偏差约为 128 * 0.1 * sqrt(2/π) ≈ 10
+	total := 0
+	for _, v := range noisy {
+		total += abs(int(v) - 128)
+	}
+	if mean := float64(total) / float64(len(noisy)); mean < 5 || mean > 15 {
+		t.Fatalf("mean deviation is %.1f, want about 10", mean)
+	}
+}
@@ -0,0 +1,89 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+)
+
+// Step is one stage of a pipeline: an operation name and its params.
+type Step struct {
+	Operation string
+	Params    Params
+}
+
+// StepResult is reported after every pipeline step.
+type StepResult struct {
+	Index     int
+	Operation string
+	Image     image.Image
+	Metadata  Metadata
+	Elapsed   time.Duration
+}
+
+// Pipeline chains operations in memory. Each step receives the output of
+// the previous one; two-image steps take Second as their second input.
+type Pipeline struct {
+	Steps  []Step
+	Second image.Image
+}
+
+// Validate checks that every step names a registered operation and that
+// two-image steps have a second input.
+func (p *Pipeline) Validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("pipeline has no steps")
+	}
+	for i, step := range p.Steps {
+		op, ok := Lookup(step.Operation)
+		if !ok {
+			return fmt.Errorf("step %d: %w: %q", i+1, ErrUnknownOperation, step.Operation)
+		}
+		if op.Arity > 2 || (op.Arity == 2 && p.Second == nil) {
+			return fmt.Errorf("step %d: %s needs a second image", i+1, op.Name)
+		}
+	}
+	return nil
+}
+
+// Run applies every step to img in order. onStep, if not nil, is called
+// with the intermediate result of each step.
+func (p *Pipeline) Run(img image.Image, onStep func(StepResult)) (image.Image, error) {
+	return p.RunContext(context.Background(), img, onStep)
+}
+
+// RunContext is like Run but stops between and inside steps when ctx is
+// cancelled. Progress is reported across all steps, and the metadata of all
+// steps is merged into the map installed with WithMetadata, later steps
+// overwriting earlier ones.
+func (p *Pipeline) RunContext(ctx context.Context, img image.Image, onStep func(StepResult)) (image.Image, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	n := float64(len(p.Steps))
+	for i, step := range p.Steps {
+		op, _ := Lookup(step.Operation)
+		images := []image.Image{img}
+		if op.Arity == 2 {
+			images = append(images, p.Second)
+		}
+
+		start := time.Now()
+		stepCtx, metadata := WithMetadata(subProgress(ctx, float64(i)/n, float64(i+1)/n))
+		result, err := op.RunContext(stepCtx, images, step.Params)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, op.Name, err)
+		}
+		img = result
+		for key, value := range metadata {
+			reportMetadata(ctx, key, value)
+		}
+
+		if onStep != nil {
+			onStep(StepResult{Index: i, Operation: op.Name, Image: img, Metadata: metadata, Elapsed: time.Since(start)})
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,303 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+)
+
+// HistogramRankArea 窗口面积达到该值时, 秩滤波使用滑动直方图, 每个像素的
+// 计算量与窗口高度成正比, 而不是与面积成正比
+var HistogramRankArea = 9
+
+func init() {
+	window := []ParamSpec{
+		{Name: "width", Type: ParamInt, Default: 3, Min: bound(1), Description: "窗口宽度"},
+		{Name: "height", Type: ParamInt, Default: 3, Min: bound(1), Description: "窗口高度"},
+	}
+	filters := []struct {
+		name       string
+		label      string
+		percentile float64
+	}{
+		{"Median Filter", "中值滤波", 50},
+		{"Min Filter", "最小值滤波", 0},
+		{"Max Filter", "最大值滤波", 100},
+	}
+	for _, f := range filters {
+		percentile := f.percentile
+		Register(Operation{
+			Name:     f.name,
+			Label:    f.label,
+			Category: CategoryConvolution,
+			Params:   append(slices.Clone(window), neighborhoodParams()...),
+			Apply: func(a *Args) (image.Image, error) {
+				return rankFilter(a.Ctx, a.Images[0], a.Params.Int("width"), a.Params.Int("height"), percentile, neighborhoodOf(a.Params))
+			},
+		})
+	}
+
+	Register(Operation{
+		Name:     "Percentile Filter",
+		Label:    "百分位滤波",
+		Category: CategoryConvolution,
+		Params: append(append(slices.Clone(window),
+			ParamSpec{Name: "percentile", Type: ParamFloat, Default: 50.0, Min: bound(0), Max: bound(100), Description: "取窗口内第几百分位的值, 0 为最小值, 50 为中值, 100 为最大值"},
+		), neighborhoodParams()...),
+		Apply: func(a *Args) (image.Image, error) {
+			return rankFilter(a.Ctx, a.Images[0], a.Params.Int("width"), a.Params.Int("height"), a.Params.Float("percentile"), neighborhoodOf(a.Params))
+		},
+	})
+
+	Register(Operation{
+		Name:     "Adaptive Median Filter",
+		Label:    "自适应中值滤波",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "maxSize", Type: ParamInt, Default: 7, Min: bound(3), Description: "窗口最大边长, 奇数"},
+		}, neighborhoodParams()[:2]...), // 窗口总是居中, 没有锚点参数
+		Apply: func(a *Args) (image.Image, error) {
+			return adaptiveMedian(a.Ctx, a.Images[0], a.Params.Int("maxSize"), neighborhoodOf(a.Params))
+		},
+	})
+}
+
+// RankFilter replaces every color channel of every pixel with the given
+// percentile of the width x height window around it: 0 is the minimum, 50
+// the median and 100 the maximum. Binary masks stay masks.
+func RankFilter(img image.Image, width, height int, percentile float64, nb Neighborhood) (image.Image, error) {
+	return rankFilter(context.Background(), img, width, height, percentile, nb)
+}
+
+func rankFilter(ctx context.Context, img image.Image, width, height int, percentile float64, nb Neighborhood) (image.Image, error) {
+	if width < 1 || height < 1 {
+		return nil, &ParamError{Param: "width", Reason: "the window must be at least 1x1"}
+	}
+	if width > MaxKernelSize || height > MaxKernelSize {
+		return nil, &ParamError{Param: "width", Reason: fmt.Sprintf("the window is %dx%d, the maximum is %dx%d", width, height, MaxKernelSize, MaxKernelSize)}
+	}
+	if percentile < 0 || percentile > 100 {
+		return nil, &ParamError{Param: "percentile", Reason: "must be between 0 and 100"}
+	}
+
+	src := pixelsOf(img)
+	var dst *Buffer
+	var err error
+	area := width * height
+	// NaN 能通过上面的检查, 转换结果不确定; 限制在窗口内, 否则直方图会越界
+	rank := clamp(int(math.Round(percentile/100*float64(area-1))), 0, area-1)
+	switch {
+	case rank == 0 || rank == area-1:
+		// 最小值和最大值与矩形结构元素的腐蚀和膨胀相同
+		dst, err = morphStep(ctx, src, RectElement(width, height), rank > 0, nb)
+	default:
+		dst, err = rankStep(ctx, src, width, height, rank, nb)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if IsMask(img) {
+		return maskOf(dst), nil
+	}
+	return dst.Image(), nil
+}
+
+// rankStep 每个颜色通道取窗口内从小到大第 rank 个值 (从 0 开始), alpha 取锚点处的值
+func rankStep(ctx context.Context, src *Buffer, width, height, rank int, nb Neighborhood) (*Buffer, error) {
+	ax, ay, err := nb.anchor(width, height)
+	if err != nil {
+		return nil, err
+	}
+	padded, bounds, err := nb.pad(ctx, src, width, height)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	n := min(ch, 3)
+
+	if width*height >= HistogramRankArea {
+		err = parallelRows(ctx, dst.Height, func(y0, y1 int) {
+			var hists [3]rankHistogram
+			for y := y0; y < y1; y++ {
+				for c := 0; c < n; c++ {
+					hists[c].reset(rank)
+				}
+				// 第一个窗口完整统计, 之后每向右移动一列只更新进出的两列
+				for ky := 0; ky < height; ky++ {
+					row := padded.Row(y + ky)
+					for kx := 0; kx < width; kx++ {
+						for c := 0; c < n; c++ {
+							hists[c].add(row[kx*ch+c])
+						}
+					}
+				}
+				out := dst.Row(y)
+				for x := 0; x < dst.Width; x++ {
+					if x > 0 {
+						for ky := 0; ky < height; ky++ {
+							row := padded.Row(y + ky)
+							for c := 0; c < n; c++ {
+								hists[c].remove(row[(x-1)*ch+c])
+								hists[c].add(row[(x+width-1)*ch+c])
+							}
+						}
+					}
+					for c := 0; c < n; c++ {
+						out[x*ch+c] = hists[c].value()
+					}
+				}
+			}
+		})
+	} else {
+		err = parallelRows(ctx, dst.Height, func(y0, y1 int) {
+			values := make([]uint8, width*height)
+			for y := y0; y < y1; y++ {
+				out := dst.Row(y)
+				for x := 0; x < dst.Width; x++ {
+					for c := 0; c < n; c++ {
+						values = values[:0]
+						for ky := 0; ky < height; ky++ {
+							row := padded.Row(y + ky)
+							for kx := 0; kx < width; kx++ {
+								values = append(values, row[(x+kx)*ch+c])
+							}
+						}
+						slices.Sort(values)
+						out[x*ch+c] = values[rank]
+					}
+				}
+			}
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ch == 4 {
+		for y := 0; y < dst.Height; y++ {
+			in, out := padded.Row(y + ay)[ax*ch:], dst.Row(y)
+			for i := 3; i < len(out); i += ch {
+				out[i] = in[i]
+			}
+		}
+	}
+	return dst, nil
+}
+
+// rankHistogram 滑动窗口的直方图. 除计数外记录当前结果 v 和小于 v 的值的个数,
+// 窗口移动后 v 只需要在相邻的灰度级之间移动
+type rankHistogram struct {
+	counts [256]int
+	rank   int
+	v      int
+	below  int
+}
+
+func (h *rankHistogram) reset(rank int) {
+	*h = rankHistogram{rank: rank}
+}
+
+func (h *rankHistogram) add(v uint8) {
+	h.counts[v]++
+	if int(v) < h.v {
+		h.below++
+	}
+}
+
+func (h *rankHistogram) remove(v uint8) {
+	h.counts[v]--
+	if int(v) < h.v {
+		h.below--
+	}
+}
+
+// value 返回使小于它的值不超过 rank 个、不大于它的值超过 rank 个的灰度级
+func (h *rankHistogram) value() uint8 {
+	for h.below > h.rank {
+		h.v--
+		h.below -= h.counts[h.v]
+	}
+	for h.below+h.counts[h.v] <= h.rank {
+		h.below += h.counts[h.v]
+		h.v++
+	}
+	return uint8(h.v)
+}
+
+// AdaptiveMedian removes impulse noise while keeping detail. For every
+// pixel the window grows from 3x3 up to maxSize x maxSize until its median
+// is not an impulse; the pixel is replaced by the median only when the
+// pixel itself is the minimum or maximum of that window.
+func AdaptiveMedian(img image.Image, maxSize int, nb Neighborhood) (image.Image, error) {
+	return adaptiveMedian(context.Background(), img, maxSize, nb)
+}
+
+func adaptiveMedian(ctx context.Context, img image.Image, maxSize int, nb Neighborhood) (image.Image, error) {
+	if maxSize < 3 || maxSize%2 == 0 {
+		return nil, &ParamError{Param: "maxSize", Reason: "must be an odd number of at least 3"}
+	}
+	if maxSize > MaxKernelSize {
+		return nil, &ParamError{Param: "maxSize", Reason: fmt.Sprintf("must be at most %d", MaxKernelSize)}
+	}
+
+	nb.AnchorX, nb.AnchorY = -1, -1
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(ctx, src, maxSize, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	n := min(ch, 3)
+	r := maxSize / 2
+
+	err = parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		values := make([]uint8, 0, maxSize*maxSize)
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			center := padded.Row(y + r)[r*ch:]
+			for x := 0; x < dst.Width; x++ {
+				for c := 0; c < ch; c++ {
+					z := center[x*ch+c]
+					out[x*ch+c] = z
+					if c >= n {
+						continue
+					}
+					for s := 1; s <= r; s++ {
+						values = values[:0]
+						for ky := r - s; ky <= r+s; ky++ {
+							row := padded.Row(y + ky)
+							for kx := r - s; kx <= r+s; kx++ {
+								values = append(values, row[(x+kx)*ch+c])
+							}
+						}
+						slices.Sort(values)
+						lo, med, hi := values[0], values[len(values)/2], values[len(values)-1]
+						if lo < med && med < hi {
+							// 中值不是噪声: 像素本身也不是极值时保留
+							if z == lo || z == hi {
+								out[x*ch+c] = med
+							}
+							break
+						}
+						if s == r {
+							out[x*ch+c] = med
+						}
+					}
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if IsMask(img) {
+		return maskOf(dst), nil
+	}
+	return dst.Image(), nil
+}
@@ -0,0 +1,129 @@
+package algorithms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRankFilterNaNPercentile(t *testing.T) {
+	img := testImages()["rgba"]
+	got, err := RankFilter(img, 5, 5, math.NaN(), DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 转换 NaN 的结果依赖平台, 只要求结果是窗口内的某个极值
+	minimum, err := RankFilter(img, 5, 5, 0, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	maximum, err := RankFilter(img, 5, 5, 100, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pix := pixelsOf(got).Pix
+	if !bytes.Equal(pix, pixelsOf(minimum).Pix) && !bytes.Equal(pix, pixelsOf(maximum).Pix) {
+		t.Fatal("NaN percentile gives neither the minimum nor the maximum")
+	}
+}
+
+// naiveRank 对每个像素排序整个窗口, 作为滑动直方图和腐蚀膨胀实现的参照
+func naiveRank(t *testing.T, img image.Image, width, height int, percentile float64, nb Neighborhood) []uint8 {
+	t.Helper()
+	ax, ay, err := nb.anchor(width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(context.Background(), src, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := src.Channels
+	rank := int(math.Round(percentile / 100 * float64(width*height-1)))
+	var out []uint8
+	values := make([]uint8, 0, width*height)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			for c := 0; c < ch; c++ {
+				if c == 3 {
+					out = append(out, padded.Row(y + ay)[(x+ax)*ch+c])
+					continue
+				}
+				values = values[:0]
+				for ky := 0; ky < height; ky++ {
+					for kx := 0; kx < width; kx++ {
+						values = append(values, padded.Row(y + ky)[(x+kx)*ch+c])
+					}
+				}
+				slices.Sort(values)
+				out = append(out, values[rank])
+			}
+		}
+	}
+	return out
+}
+
+func TestRankFilterMatchesSort(t *testing.T) {
+	windows := [][2]int{{1, 1}, {2, 2}, {3, 1}, {3, 3}, {5, 3}, {4, 6}, {7, 7}}
+	percentiles := []float64{0, 25, 50, 73, 100}
+	for name, img := range testImages() {
+		for _, w := range windows {
+			for _, border := range BorderModes {
+				nb := Neighborhood{Border: BorderMode(border), BorderValue: 200, AnchorX: -1, AnchorY: -1}
+				t.Run(fmt.Sprintf("%s/%dx%d/%s", name, w[0], w[1], border), func(t *testing.T) {
+					for _, p := range percentiles {
+						got, err := RankFilter(img, w[0], w[1], p, nb)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if want := naiveRank(t, img, w[0], w[1], p, nb); !bytes.Equal(pixelsOf(got).Pix, want) {
+							t.Fatalf("percentile %v differs from sorting the window", p)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestAdaptiveMedian(t *testing.T) {
+	// 平坦图像上的椒盐噪声被完全去除
+	flat := image.NewGray(image.Rect(0, 0, 40, 30))
+	for i := range flat.Pix {
+		flat.Pix[i] = 100
+	}
+	noisy, err := AddNoise(flat, NoiseOptions{Kind: NoiseSaltPepper, Density: 0.1, SaltRatio: 0.5, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := AdaptiveMedian(noisy, 7, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pixelsOf(got).Pix, flat.Pix) {
+		t.Fatal("salt and pepper noise was not removed")
+	}
+
+	// 水平渐变中的像素都不是窗口的极值, 保持不变
+	ramp := image.NewGray(image.Rect(0, 0, 40, 30))
+	for i := range ramp.Pix {
+		ramp.Pix[i] = uint8(10 + 5*(i%40))
+	}
+	got, err = AdaptiveMedian(ramp, 5, Neighborhood{Border: BorderReplicate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := pixelsOf(got)
+	for y := 0; y < 30; y++ {
+		for x := 1; x < 39; x++ {
+			if v, want := inner.Row(y)[x], ramp.Pix[y*40+x]; v != want {
+				t.Fatalf("(%d, %d) = %d, want %d", x, y, v, want)
+			}
+		}
+	}
+}
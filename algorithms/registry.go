@@ -0,0 +1,428 @@
+package algorithms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 算子分类，每个分类对应一个处理接口
+const (
+	CategoryMixed          = "mixed"
+	CategoryArithmetic     = "arithmetic"
+	CategoryBitwise        = "bitwise"
+	CategoryConvolution    = "convolution"
+	CategoryTransformation = "transformation"
+	CategoryThreshold      = "threshold"
+	CategoryMorphology     = "morphology"
+	CategoryGeometry       = "geometry"
+)
+
+// ParamType 参数类型
+type ParamType string
+
+const (
+	ParamFloat ParamType = "float"
+	ParamInt   ParamType = "int"
+	ParamBool  ParamType = "bool"
+	ParamEnum  ParamType = "enum"
+	// ParamKernel 卷积核, 以 JSON 二维数组表示
+	ParamKernel ParamType = "kernel"
+	// ParamPoints 点列表, 以 JSON 数组 [[x, y], ...] 表示, 点数不限
+	ParamPoints ParamType = "points"
+)
+
+// ParamSpec describes one typed parameter of an operation.
+type ParamSpec struct {
+	Name        string    `json:"name"`
+	Type        ParamType `json:"type"`
+	Description string    `json:"description,omitempty"`
+	Default     any       `json:"default,omitempty"`
+	Required    bool      `json:"required,omitempty"`
+	Min         *float64  `json:"min,omitempty"`
+	Max         *float64  `json:"max,omitempty"`
+	Options     []string  `json:"options,omitempty"`
+	// Advanced 可选的高级参数, 前端不逐个询问
+	Advanced bool `json:"advanced,omitempty"`
+	// Aliases 兼容旧的表单字段名 (例如 "param")
+	Aliases []string `json:"-"`
+}
+
+// Operation is a single registered image processing algorithm.
+type Operation struct {
+	Name     string      `json:"name"`
+	Label    string      `json:"label,omitempty"`
+	Category string      `json:"category"`
+	Arity    int         `json:"arity"`
+	Params   []ParamSpec `json:"params"`
+	Apply    ApplyFunc   `json:"-"`
+
+	order int
+}
+
+// ApplyFunc 算子的具体实现
+type ApplyFunc func(a *Args) (image.Image, error)
+
+// Args 传递给算子实现的输入图像和已解析的参数
+type Args struct {
+	Ctx    context.Context
+	Images []image.Image
+	Params Params
+}
+
+// Params holds parsed parameter values keyed by parameter name.
+type Params map[string]any
+
+// ParamError is returned when a parameter is missing or invalid.
+type ParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid parameter %q: %s", e.Param, e.Reason)
+}
+
+// ErrUnknownOperation is returned when no operation is registered under a name.
+var ErrUnknownOperation = errors.New("unknown operation")
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Operation{}
+)
+
+// Register adds an operation to the registry. It panics on duplicate names,
+// since registration happens in init functions.
+func Register(op Operation) {
+	if op.Name == "" || op.Apply == nil {
+		panic("algorithms: operation needs a name and an implementation")
+	}
+	if op.Arity == 0 {
+		op.Arity = 1
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[op.Name]; exists {
+		panic("algorithms: duplicate operation " + op.Name)
+	}
+	op.order = len(registry)
+	registry[op.Name] = &op
+}
+
+// Lookup returns the operation registered under name.
+func Lookup(name string) (*Operation, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	op, ok := registry[name]
+	return op, ok
+}
+
+// Operations returns all registered operations of a category, or every
+// operation when category is empty, in a stable order.
+func Operations(category string) []*Operation {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var ops []*Operation
+	for _, op := range registry {
+		if category == "" || op.Category == category {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Category != ops[j].Category {
+			return categoryRank(ops[i].Category) < categoryRank(ops[j].Category)
+		}
+		return ops[i].order < ops[j].order
+	})
+	return ops
+}
+
+// Apply runs the named operation on already decoded images. Missing params
+// take their defaults.
+func Apply(name string, images []image.Image, params Params) (image.Image, error) {
+	op, ok := Lookup(name)
+	if !ok {
+		return nil, ErrUnknownOperation
+	}
+	return op.Run(images, params)
+}
+
+// Run applies op to the given images. params may come from ParseParams or be
+// built by hand; missing values take their defaults.
+func (op *Operation) Run(images []image.Image, params Params) (image.Image, error) {
+	return op.RunContext(context.Background(), images, params)
+}
+
+// RunContext is like Run but stops early when ctx is cancelled and reports
+// progress to the function installed with WithProgress.
+func (op *Operation) RunContext(ctx context.Context, images []image.Image, params Params) (image.Image, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(images) != op.Arity {
+		return nil, fmt.Errorf("%s expects %d image(s), got %d", op.Name, op.Arity, len(images))
+	}
+	params, err := op.complete(params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := op.Apply(&Args{Ctx: ctx, Images: images, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	reportProgress(ctx, 1)
+	return result, nil
+}
+
+// complete 填充默认值并检查必填参数和取值范围
+func (op *Operation) complete(params Params) (Params, error) {
+	complete := Params{}
+	for _, spec := range op.Params {
+		value, ok := params[spec.Name]
+		if !ok {
+			if spec.Required {
+				return nil, &ParamError{Param: spec.Name, Reason: "required"}
+			}
+			if spec.Default != nil {
+				complete[spec.Name] = spec.Default
+			}
+			continue
+		}
+		switch spec.Type {
+		case ParamFloat:
+			if err := spec.checkRange(params.Float(spec.Name)); err != nil {
+				return nil, err
+			}
+		case ParamInt:
+			if err := spec.checkRange(float64(params.Int(spec.Name))); err != nil {
+				return nil, err
+			}
+		case ParamKernel:
+			// 直接调用 Run 时也可以传入 [][]float64
+			if rows, ok := value.([][]float64); ok {
+				k, err := NewKernel(rows)
+				if err != nil {
+					return nil, &ParamError{Param: spec.Name, Reason: err.Error()}
+				}
+				value = k
+			}
+			if _, ok := value.(*Kernel); !ok {
+				return nil, &ParamError{Param: spec.Name, Reason: "not a kernel"}
+			}
+		case ParamPoints:
+			if _, ok := value.([][2]float64); !ok {
+				return nil, &ParamError{Param: spec.Name, Reason: "not a list of points"}
+			}
+		}
+		complete[spec.Name] = value
+	}
+	return complete, nil
+}
+
+// ParseParams parses raw string values into typed params according to the
+// operation's schema. lookup returns the raw value and whether it was set.
+// Missing values are left out; Run fills in defaults.
+func (op *Operation) ParseParams(lookup func(name string) (string, bool)) (Params, error) {
+	params := Params{}
+	for _, spec := range op.Params {
+		raw, ok := lookup(spec.Name)
+		for _, alias := range spec.Aliases {
+			if ok {
+				break
+			}
+			raw, ok = lookup(alias)
+		}
+		raw = strings.TrimSpace(raw)
+		if !ok || raw == "" {
+			continue
+		}
+
+		value, err := spec.parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		params[spec.Name] = value
+	}
+	return params, nil
+}
+
+func (spec ParamSpec) parse(raw string) (any, error) {
+	switch spec.Type {
+	case ParamFloat:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, &ParamError{Param: spec.Name, Reason: "not a number"}
+		}
+		return v, spec.checkRange(v)
+	case ParamInt:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, &ParamError{Param: spec.Name, Reason: "not an integer"}
+		}
+		return v, spec.checkRange(float64(v))
+	case ParamBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &ParamError{Param: spec.Name, Reason: "not a boolean"}
+		}
+		return v, nil
+	case ParamEnum:
+		for _, option := range spec.Options {
+			if strings.EqualFold(option, raw) {
+				return option, nil
+			}
+		}
+		return nil, &ParamError{Param: spec.Name, Reason: "must be one of " + strings.Join(spec.Options, ", ")}
+	case ParamKernel:
+		k, err := ParseKernel(raw)
+		if err != nil {
+			return nil, &ParamError{Param: spec.Name, Reason: err.Error()}
+		}
+		return k, nil
+	case ParamPoints:
+		points, err := ParsePoints(raw)
+		if err != nil {
+			return nil, &ParamError{Param: spec.Name, Reason: err.Error()}
+		}
+		return points, nil
+	default:
+		return raw, nil
+	}
+}
+
+// checkRange 检查取值范围. strconv.ParseFloat 接受 NaN 和 Inf, NaN 与任何边界比较都为 false, 需要单独拒绝
+func (spec ParamSpec) checkRange(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return &ParamError{Param: spec.Name, Reason: "must be a finite number"}
+	}
+	if spec.Min != nil && v < *spec.Min {
+		return &ParamError{Param: spec.Name, Reason: fmt.Sprintf("must be >= %v", *spec.Min)}
+	}
+	if spec.Max != nil && v > *spec.Max {
+		return &ParamError{Param: spec.Name, Reason: fmt.Sprintf("must be <= %v", *spec.Max)}
+	}
+	return nil
+}
+
+// Float returns a float parameter, or 0 when it is not set.
+func (p Params) Float(name string) float64 {
+	switch v := p[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+// Int returns an integer parameter, or 0 when it is not set.
+func (p Params) Int(name string) int {
+	switch v := p[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// Bool returns a boolean parameter.
+func (p Params) Bool(name string) bool {
+	v, _ := p[name].(bool)
+	return v
+}
+
+// String returns a string or enum parameter.
+func (p Params) String(name string) string {
+	v, _ := p[name].(string)
+	return v
+}
+
+// Kernel returns a kernel parameter, or nil when it is not set.
+func (p Params) Kernel(name string) *Kernel {
+	v, _ := p[name].(*Kernel)
+	return v
+}
+
+// Points returns a point list parameter, or nil when it is not set.
+func (p Params) Points(name string) [][2]float64 {
+	v, _ := p[name].([][2]float64)
+	return v
+}
+
+// Has reports whether a parameter was set or defaulted.
+func (p Params) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+// bound 用于填写 ParamSpec 的 Min/Max
+func bound(v float64) *float64 {
+	return &v
+}
+
+func categoryRank(category string) int {
+	for i, c := range []string{CategoryMixed, CategoryArithmetic, CategoryBitwise, CategoryConvolution, CategoryTransformation, CategoryThreshold, CategoryMorphology, CategoryGeometry} {
+		if c == category {
+			return i
+		}
+	}
+	return 1 << 10
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context under which running operations report their
+// progress, from 0 to 1, to fn.
+func WithProgress(ctx context.Context, fn func(done float64)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, done float64) {
+	if fn, ok := ctx.Value(progressKey{}).(func(float64)); ok {
+		fn(done)
+	}
+}
+
+// subProgress 将 [0, 1] 的进度映射到 [from, to], 用于多步骤的流水线
+func subProgress(ctx context.Context, from, to float64) context.Context {
+	fn, ok := ctx.Value(progressKey{}).(func(float64))
+	if !ok {
+		return ctx
+	}
+	return WithProgress(ctx, func(done float64) {
+		fn(from + (to-from)*done)
+	})
+}
+
+// Metadata holds values an operation computes besides its image, such as an
+// automatically chosen threshold.
+type Metadata map[string]any
+
+type metadataKey struct{}
+
+// WithMetadata returns a context under which running operations record
+// their metadata in the returned map. Read the map only after the operation
+// has returned.
+func WithMetadata(ctx context.Context) (context.Context, Metadata) {
+	m := Metadata{}
+	return context.WithValue(ctx, metadataKey{}, m), m
+}
+
+// reportMetadata 记录一项元数据, 没有通过 WithMetadata 收集时忽略
+func reportMetadata(ctx context.Context, key string, value any) {
+	if m, ok := ctx.Value(metadataKey{}).(Metadata); ok {
+		m[key] = value
+	}
+}
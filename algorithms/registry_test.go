@@ -0,0 +1,31 @@
+package algorithms
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestFloatParamsMustBeFinite(t *testing.T) {
+	op, ok := Lookup("Percentile Filter")
+	if !ok {
+		t.Fatal("Percentile Filter is not registered")
+	}
+	for _, raw := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "infinity"} {
+		_, err := op.ParseParams(func(name string) (string, bool) {
+			return raw, name == "percentile"
+		})
+		if _, ok := err.(*ParamError); !ok {
+			t.Errorf("percentile=%s: got %v, want a *ParamError", raw, err)
+		}
+	}
+
+	// 直接调用 Run 传入的值同样检查
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	if _, err := op.Run([]image.Image{img}, Params{"percentile": math.NaN()}); err == nil {
+		t.Error("Run accepted a NaN percentile")
+	}
+	if _, err := Apply("Resize", []image.Image{img}, Params{"scale": math.Inf(1)}); err == nil {
+		t.Error("Resize accepted an infinite scale")
+	}
+}
@@ -0,0 +1,53 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+)
+
+// convolveSeparable 先用 row 做水平一维卷积, 再用 col 做垂直一维卷积,
+// 每个像素的计算量从 O(kw*kh) 降到 O(kw+kh)
+func convolveSeparable(ctx context.Context, src, dst *Buffer, col, row []float64, scale, bias float64) error {
+	ch := src.Channels
+	n := min(ch, 3)
+
+	// 水平方向的中间结果, 宽度与输出相同, 高度与扩展后的输入相同
+	tmp := NewFloatBuffer(image.Rect(0, 0, dst.Width, src.Height), n, Interleaved, src.Model)
+	err := parallelRows(subProgress(ctx, 0, 0.5), src.Height, func(y0, y1 int) {
+		var sums [3]float64
+		for y := y0; y < y1; y++ {
+			in, out := src.Row(y), tmp.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				sums = [3]float64{}
+				for kx, weight := range row {
+					i := (x + kx) * ch
+					for c := 0; c < n; c++ {
+						sums[c] += float64(in[i+c]) * weight
+					}
+				}
+				for c := 0; c < n; c++ {
+					out[x*n+c] = float32(sums[c])
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return parallelRows(subProgress(ctx, 0.5, 1), dst.Height, func(y0, y1 int) {
+		sums := make([]float64, dst.Width*n)
+		for y := y0; y < y1; y++ {
+			clear(sums)
+			for ky, weight := range col {
+				for i, v := range tmp.Row(y + ky) {
+					sums[i] += float64(v) * weight
+				}
+			}
+			out := dst.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				storeConvolved(out[x*ch:(x+1)*ch], sums[x*n:(x+1)*n], scale, bias)
+			}
+		}
+	})
+}
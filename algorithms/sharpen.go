@@ -0,0 +1,109 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+)
+
+func init() {
+	Register(Operation{
+		Name:     "Unsharp Mask",
+		Label:    "USM 锐化",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "radius", Type: ParamFloat, Default: 1.0, Min: bound(0.01), Description: "模糊的高斯标准差, 决定被加强的细节的尺度"},
+			{Name: "amount", Type: ParamFloat, Default: 1.0, Min: bound(0), Description: "锐化强度, 细节乘以该值后加回原图"},
+			{Name: "threshold", Type: ParamInt, Default: 0, Min: bound(0), Max: bound(255), Description: "与模糊结果相差小于该值的像素不锐化, 避免放大噪声"},
+		}, neighborhoodParams()[:2]...), // 模糊必须与原图对齐, 没有锚点参数
+		Apply: func(a *Args) (image.Image, error) {
+			return unsharpMask(a.Ctx, a.Images[0], a.Params.Float("radius"), a.Params.Float("amount"), a.Params.Int("threshold"), neighborhoodOf(a.Params))
+		},
+	})
+	Register(Operation{
+		Name:     "High-Boost Filter",
+		Label:    "高提升滤波",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "k", Type: ParamFloat, Default: 2.0, Min: bound(0), Description: "提升系数, 原图加上 k 倍的 (原图 - 模糊); 1 即普通的反锐化掩模"},
+			{Name: "sigma", Type: ParamFloat, Default: 1.0, Min: bound(0.01), Description: "模糊的高斯标准差"},
+		}, neighborhoodParams()[:2]...),
+		Apply: func(a *Args) (image.Image, error) {
+			return highBoost(a.Ctx, a.Images[0], a.Params.Float("k"), a.Params.Float("sigma"), neighborhoodOf(a.Params))
+		},
+	})
+}
+
+// UnsharpMask sharpens img by adding back amount times the difference
+// between img and its Gaussian blur of standard deviation radius. Channels
+// that differ from the blur by less than threshold are left unchanged.
+func UnsharpMask(img image.Image, radius, amount float64, threshold int, nb Neighborhood) (image.Image, error) {
+	return unsharpMask(context.Background(), img, radius, amount, threshold, nb)
+}
+
+// HighBoost returns img + k * (img - blur), where blur is a Gaussian blur
+// of standard deviation sigma. It is UnsharpMask with a threshold of 0: k = 1
+// is plain unsharp masking and larger values emphasize detail more.
+func HighBoost(img image.Image, k, sigma float64, nb Neighborhood) (image.Image, error) {
+	return UnsharpMask(img, sigma, k, 0, nb)
+}
+
+func highBoost(ctx context.Context, img image.Image, k, sigma float64, nb Neighborhood) (image.Image, error) {
+	return unsharpMask(ctx, img, sigma, k, 0, nb)
+}
+
+func unsharpMask(ctx context.Context, img image.Image, sigma, amount float64, threshold int, nb Neighborhood) (image.Image, error) {
+	if amount < 0 {
+		return nil, &ParamError{Param: "amount", Reason: "must not be negative"}
+	}
+	kernel, err := gaussianKernelOf(sigma, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	nb.AnchorX, nb.AnchorY = -1, -1
+	orig := pixelsOf(img).Image()
+	blurred, err := convolve(subProgress(ctx, 0, 0.6), orig, kernel, 1, 0, nb, MethodAuto)
+	if err != nil {
+		return nil, err
+	}
+	// 裁剪模式下模糊结果比原图小, 原图取相同的区域
+	if r := blurred.Bounds(); r != orig.Bounds() {
+		orig = orig.(interface {
+			SubImage(image.Rectangle) image.Image
+		}).SubImage(r)
+	}
+
+	// 算术减法在 0 处截断, 原图 - 模糊只保留比模糊亮的细节, 所以正负两部分分别相减:
+	// 结果 = 原图 + amount*(原图-模糊) - amount*(模糊-原图).
+	// 每个通道两部分中至多一个不为 0, 先加后减与不截断的计算结果相同
+	ctx = subProgress(ctx, 0.6, 1)
+	brighter, err := combine(ctx, orig, blurred, subtractPixels)
+	if err != nil {
+		return nil, err
+	}
+	darker, err := combine(ctx, blurred, orig, subtractPixels)
+	if err != nil {
+		return nil, err
+	}
+	// 细节乘以 amount, 小于 threshold 的细节视为噪声, 不加强
+	scale := func(px []uint8) {
+		for c := 0; c < colorChannels(px); c++ {
+			if int(px[c]) < threshold {
+				px[c] = 0
+			} else {
+				px[c] = clampFloat(float32(amount * float64(px[c])))
+			}
+		}
+	}
+	if brighter, err = mapPixels(ctx, brighter, scale); err != nil {
+		return nil, err
+	}
+	if darker, err = mapPixels(ctx, darker, scale); err != nil {
+		return nil, err
+	}
+	sharpened, err := combine(ctx, orig, brighter, addPixels)
+	if err != nil {
+		return nil, err
+	}
+	return combine(ctx, sharpened, darker, subtractPixels)
+}
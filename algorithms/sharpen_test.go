@@ -0,0 +1,114 @@
+package algorithms
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+)
+
+// naiveUnsharp 逐个通道计算 原图 ± amount*|原图-模糊|, 细节小于 threshold 时不变
+func naiveUnsharp(t *testing.T, img image.Image, sigma, amount float64, threshold int, nb Neighborhood) []uint8 {
+	t.Helper()
+	nb.AnchorX, nb.AnchorY = -1, -1
+	blurred, err := GaussianBlur(img, sigma, nb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, blur := pixelsOf(img), pixelsOf(blurred)
+	out := make([]uint8, len(src.Pix))
+	for i, o := range src.Pix {
+		if src.Channels == 4 && i%4 == 3 {
+			out[i] = o
+			continue
+		}
+		d := int(o) - int(blur.Pix[i])
+		detail := 0
+		if abs(d) >= threshold {
+			detail = int(clampFloat(float32(amount * float64(abs(d)))))
+		}
+		if d < 0 {
+			detail = -detail
+		}
+		out[i] = uint8(clamp(int(o)+detail, 0, 255))
+	}
+	return out
+}
+
+func TestUnsharpMask(t *testing.T) {
+	tests := []struct {
+		sigma     float64
+		amount    float64
+		threshold int
+	}{
+		{1, 1, 0},
+		{2, 0.5, 0},
+		{1, 3, 0},
+		{1.5, 1, 20},
+	}
+	for name, img := range testImages() {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%v/%v/%d", name, tt.sigma, tt.amount, tt.threshold), func(t *testing.T) {
+				got, err := UnsharpMask(img, tt.sigma, tt.amount, tt.threshold, DefaultNeighborhood)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := naiveUnsharp(t, img, tt.sigma, tt.amount, tt.threshold, DefaultNeighborhood)
+				if !bytes.Equal(pixelsOf(got).Pix, want) {
+					t.Fatal("differs from adding back the scaled detail")
+				}
+			})
+		}
+	}
+}
+
+func TestUnsharpMaskStepEdge(t *testing.T) {
+	// 50 和 200 之间的台阶: 暗侧变暗, 亮侧变亮, 远离台阶的像素不变
+	img := image.NewGray(image.Rect(0, 0, 20, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 50
+		if i%20 >= 10 {
+			img.Pix[i] = 200
+		}
+	}
+	got, err := UnsharpMask(img, 1, 1, 0, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := got.(*image.Gray)
+	for y := 0; y < 4; y++ {
+		if v := out.GrayAt(9, y).Y; v >= 50 {
+			t.Fatalf("dark side of the edge = %d, want below 50", v)
+		}
+		if v := out.GrayAt(10, y).Y; v <= 200 {
+			t.Fatalf("bright side of the edge = %d, want above 200", v)
+		}
+		if a, b := out.GrayAt(0, y).Y, out.GrayAt(19, y).Y; a != 50 || b != 200 {
+			t.Fatalf("flat areas = %d and %d, want 50 and 200", a, b)
+		}
+	}
+
+	// amount 为 0 时不变
+	got, err = UnsharpMask(img, 1, 0, 0, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pixelsOf(got).Pix, img.Pix) {
+		t.Fatal("amount 0 changes the image")
+	}
+}
+
+func TestHighBoost(t *testing.T) {
+	img := testImages()["rgba"]
+	got, err := HighBoost(img, 2, 1, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := UnsharpMask(img, 1, 2, 0, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pixelsOf(got).Pix, pixelsOf(want).Pix) {
+		t.Fatal("HighBoost differs from UnsharpMask with a threshold of 0")
+	}
+}
@@ -0,0 +1,158 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+func init() {
+	Register(Operation{
+		Name:     "Convolution - Gaussian Blur",
+		Label:    "卷积 - 高斯模糊",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "sigma", Type: ParamFloat, Default: 1.0, Min: bound(0.01), Description: "高斯函数的标准差, 越大越模糊"},
+			{Name: "size", Type: ParamInt, Default: 0, Min: bound(0), Description: "卷积核边长, 0 表示由 sigma 决定 (2*ceil(3σ)+1, 不受最大卷积核尺寸限制)"},
+			{Name: "method", Type: ParamEnum, Options: ConvolutionMethods, Default: string(MethodAuto), Description: "计算方式, 结果在误差范围内相同", Advanced: true},
+		}, neighborhoodParams()...),
+		Apply: func(a *Args) (image.Image, error) {
+			kernel, err := gaussianKernelOf(a.Params.Float("sigma"), a.Params.Int("size"))
+			if err != nil {
+				return nil, err
+			}
+			return convolve(a.Ctx, a.Images[0], kernel, 1, 0, neighborhoodOf(a.Params), ConvolutionMethod(a.Params.String("method")))
+		},
+	})
+	Register(Operation{
+		Name:     "Bilateral Filter",
+		Label:    "双边滤波",
+		Category: CategoryConvolution,
+		Params: append([]ParamSpec{
+			{Name: "sigmaSpace", Type: ParamFloat, Default: 3.0, Min: bound(0.01), Description: "空间距离的标准差, 单位为像素"},
+			{Name: "sigmaColor", Type: ParamFloat, Default: 30.0, Min: bound(0.01), Description: "颜色差的标准差, 越大越接近高斯模糊"},
+			{Name: "diameter", Type: ParamInt, Default: 0, Min: bound(0), Description: "邻域直径, 0 表示由 sigmaSpace 决定"},
+		}, neighborhoodParams()[:2]...), // 窗口总是居中, 没有锚点参数
+		Apply: func(a *Args) (image.Image, error) {
+			return bilateralFilter(a.Ctx, a.Images[0], a.Params.Int("diameter"), a.Params.Float("sigmaSpace"), a.Params.Float("sigmaColor"), neighborhoodOf(a.Params))
+		},
+	})
+}
+
+// gaussianKernelOf 检查参数并生成高斯核
+func gaussianKernelOf(sigma float64, size int) (*Kernel, error) {
+	if sigma <= 0 {
+		return nil, &ParamError{Param: "sigma", Reason: "must be positive"}
+	}
+	if sigma > MaxGaussianSigma {
+		return nil, &ParamError{Param: "sigma", Reason: fmt.Sprintf("must be at most %v", MaxGaussianSigma)}
+	}
+	if size > MaxKernelSize {
+		return nil, &ParamError{Param: "size", Reason: fmt.Sprintf("must be at most %d", MaxKernelSize)}
+	}
+	return GaussianKernel(sigma, size), nil
+}
+
+// GaussianBlur blurs img with a Gaussian of standard deviation sigma, up to
+// MaxGaussianSigma. The kernel size is 2*ceil(3σ)+1.
+func GaussianBlur(img image.Image, sigma float64, nb Neighborhood) (image.Image, error) {
+	kernel, err := gaussianKernelOf(sigma, 0)
+	if err != nil {
+		return nil, err
+	}
+	return convolve(context.Background(), img, kernel, 1, 0, nb, MethodAuto)
+}
+
+// BilateralFilter smooths img while keeping edges: every neighbor within
+// diameter/2 pixels is weighted by its distance (sigmaSpace) and by its
+// color difference from the center pixel (sigmaColor). A diameter of 0 is
+// derived from sigmaSpace; even diameters are rounded up.
+func BilateralFilter(img image.Image, diameter int, sigmaSpace, sigmaColor float64, nb Neighborhood) (image.Image, error) {
+	return bilateralFilter(context.Background(), img, diameter, sigmaSpace, sigmaColor, nb)
+}
+
+func bilateralFilter(ctx context.Context, img image.Image, diameter int, sigmaSpace, sigmaColor float64, nb Neighborhood) (image.Image, error) {
+	if sigmaSpace <= 0 {
+		return nil, &ParamError{Param: "sigmaSpace", Reason: "must be positive"}
+	}
+	if sigmaColor <= 0 {
+		return nil, &ParamError{Param: "sigmaColor", Reason: "must be positive"}
+	}
+	if diameter <= 0 {
+		// 与 OpenCV 相同, 半径取 1.5σ
+		diameter = 2*int(math.Round(1.5*sigmaSpace)) + 1
+	}
+	if diameter > MaxKernelSize {
+		return nil, &ParamError{Param: "diameter", Reason: fmt.Sprintf("is %d, the maximum is %d", diameter, MaxKernelSize)}
+	}
+	r := diameter / 2
+	size := 2*r + 1
+
+	nb.AnchorX, nb.AnchorY = -1, -1
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(ctx, src, size, size)
+	if err != nil {
+		return nil, err
+	}
+	dst := NewBuffer(bounds, src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	n := min(ch, 3)
+
+	// 圆形邻域中每个位置相对于窗口左上角的偏移和空间权重
+	type tap struct {
+		offset int
+		weight float32
+	}
+	var taps []tap
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if d2 := dx*dx + dy*dy; d2 <= r*r {
+				w := math.Exp(-float64(d2) / (2 * sigmaSpace * sigmaSpace))
+				taps = append(taps, tap{(dy+r)*padded.Stride + (dx+r)*ch, float32(w)})
+			}
+		}
+	}
+	// 颜色权重按颜色差的平方和查表
+	colorWeights := make([]float32, n*255*255+1)
+	for d2 := range colorWeights {
+		colorWeights[d2] = float32(math.Exp(-float64(d2) / (2 * sigmaColor * sigmaColor)))
+	}
+
+	err = parallelRows(ctx, dst.Height, func(y0, y1 int) {
+		var sums [3]float32
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < dst.Width; x++ {
+				start := y*padded.Stride + x*ch
+				center := padded.Pix[start+r*padded.Stride+r*ch:]
+				sums = [3]float32{}
+				var total float32
+				for _, t := range taps {
+					p := padded.Pix[start+t.offset:]
+					d2 := 0
+					for c := 0; c < n; c++ {
+						d := int(p[c]) - int(center[c])
+						d2 += d * d
+					}
+					w := t.weight * colorWeights[d2]
+					total += w
+					for c := 0; c < n; c++ {
+						sums[c] += w * float32(p[c])
+					}
+				}
+				// 中心像素的权重为 1, total 不会为 0
+				for c := 0; c < n; c++ {
+					out[x*ch+c] = clampFloat(sums[c] / total)
+				}
+				if ch == 4 {
+					out[x*ch+3] = center[3]
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dst.Image(), nil
+}
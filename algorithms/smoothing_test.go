@@ -0,0 +1,130 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestGaussianKernel(t *testing.T) {
+	for _, sigma := range []float64{0.5, 1, 2.3} {
+		k := GaussianKernel(sigma, 0)
+		if size := 2*int(math.Ceil(3*sigma)) + 1; k.Width != size || k.Height != size {
+			t.Fatalf("sigma %v: size %dx%d, want %d", sigma, k.Width, k.Height, size)
+		}
+		if math.Abs(k.Sum()-1) > 1e-9 {
+			t.Fatalf("sigma %v: weights sum to %v", sigma, k.Sum())
+		}
+		// 中心右侧的权重与中心之比为 exp(-1/2σ²)
+		c := k.Width / 2
+		if got, want := k.At(c+1, c)/k.At(c, c), math.Exp(-1/(2*sigma*sigma)); math.Abs(got-want) > 1e-9 {
+			t.Fatalf("sigma %v: ratio %v, want %v", sigma, got, want)
+		}
+		if k.At(c-1, c+1) != k.At(c+1, c-1) {
+			t.Fatalf("sigma %v: kernel is not symmetric", sigma)
+		}
+	}
+}
+
+func TestGaussianBlurImpulse(t *testing.T) {
+	// 常数边界为 0 时, 单个亮点的模糊结果就是缩放后的卷积核
+	img := image.NewGray(image.Rect(0, 0, 15, 15))
+	img.Pix[7*15+7] = 200
+	got, err := GaussianBlur(img, 1, Neighborhood{Border: BorderConstant, AnchorX: -1, AnchorY: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := GaussianKernel(1, 0)
+	out := got.(*image.Gray)
+	for y := 0; y < 15; y++ {
+		for x := 0; x < 15; x++ {
+			want := 0.0
+			if dx, dy := x-7+k.Width/2, y-7+k.Height/2; dx >= 0 && dx < k.Width && dy >= 0 && dy < k.Height {
+				want = 200 * k.At(dx, dy)
+			}
+			if v := float64(out.GrayAt(x, y).Y); math.Abs(v-want) > 0.5+1e-6 {
+				t.Fatalf("(%d, %d) = %v, want %.2f", x, y, v, want)
+			}
+		}
+	}
+}
+
+// naiveBilateral 直接按定义计算双边滤波, 作为参照
+func naiveBilateral(t *testing.T, img image.Image, diameter int, sigmaSpace, sigmaColor float64, nb Neighborhood) []uint8 {
+	t.Helper()
+	r := diameter / 2
+	src := pixelsOf(img)
+	padded, bounds, err := nb.pad(context.Background(), src, 2*r+1, 2*r+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := src.Channels
+	n := min(ch, 3)
+	var out []uint8
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			center := padded.Row(y + r)[(x+r)*ch:]
+			var sums [3]float64
+			var total float64
+			for dy := -r; dy <= r; dy++ {
+				for dx := -r; dx <= r; dx++ {
+					if dx*dx+dy*dy > r*r {
+						continue
+					}
+					p := padded.Row(y + r + dy)[(x+r+dx)*ch:]
+					d2 := 0.0
+					for c := 0; c < n; c++ {
+						d := float64(p[c]) - float64(center[c])
+						d2 += d * d
+					}
+					w := math.Exp(-float64(dx*dx+dy*dy)/(2*sigmaSpace*sigmaSpace)) * math.Exp(-d2/(2*sigmaColor*sigmaColor))
+					total += w
+					for c := 0; c < n; c++ {
+						sums[c] += w * float64(p[c])
+					}
+				}
+			}
+			for c := 0; c < n; c++ {
+				out = append(out, uint8(math.Round(sums[c]/total)))
+			}
+			if ch == 4 {
+				out = append(out, center[3])
+			}
+		}
+	}
+	return out
+}
+
+func TestBilateralFilter(t *testing.T) {
+	for name, img := range testImages() {
+		for _, sigmaColor := range []float64{5, 40, 1e4} {
+			t.Run(fmt.Sprintf("%s/%v", name, sigmaColor), func(t *testing.T) {
+				nb := Neighborhood{Border: BorderReflect101, AnchorX: -1, AnchorY: -1}
+				got, err := BilateralFilter(img, 5, 2, sigmaColor, nb)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := naiveBilateral(t, img, 5, 2, sigmaColor, nb)
+				for i, v := range pixelsOf(got).Pix {
+					if abs(int(v)-int(want[i])) > 1 {
+						t.Fatalf("byte %d = %d, want %d", i, v, want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestBilateralFilterKeepsEdges(t *testing.T) {
+	// 颜色差为 255 的两侧几乎没有权重, 台阶保持不变
+	img := stepImage(16, 8)
+	got, err := BilateralFilter(img, 7, 3, 20, DefaultNeighborhood)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := maxDiff(t, got, img); d != 0 {
+		t.Fatalf("step edge changed by %d", d)
+	}
+}
@@ -0,0 +1,286 @@
+package algorithms
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"slices"
+)
+
+// ThresholdMethod 选择阈值的方法
+type ThresholdMethod string
+
+const (
+	ThresholdFixed    ThresholdMethod = "fixed"
+	ThresholdOtsu     ThresholdMethod = "otsu"
+	ThresholdTriangle ThresholdMethod = "triangle"
+	// ThresholdMean, ThresholdGaussian 自适应阈值: 每个像素与邻域的 (高斯) 加权均值比较
+	ThresholdMean     ThresholdMethod = "adaptive-mean"
+	ThresholdGaussian ThresholdMethod = "adaptive-gaussian"
+)
+
+// ThresholdOptions configures Threshold. A pixel belongs to the foreground
+// when its luminance is above the threshold, or not above it with Invert.
+type ThresholdOptions struct {
+	Method ThresholdMethod
+	// Value ThresholdFixed 使用的阈值
+	Value  float64
+	Invert bool
+	// BlockSize 自适应阈值的邻域边长, 奇数
+	BlockSize int
+	// C 自适应阈值从邻域均值中减去的常数
+	C float64
+	// Neighborhood 自适应阈值计算均值时的边界处理
+	Neighborhood Neighborhood
+}
+
+// DefaultThresholdOptions picks the threshold with Otsu's method.
+var DefaultThresholdOptions = ThresholdOptions{Method: ThresholdOtsu, Value: 127, BlockSize: 11, C: 2, Neighborhood: DefaultNeighborhood}
+
+func init() {
+	invert := ParamSpec{Name: "invert", Type: ParamBool, Default: false, Description: "亮于阈值的像素作为背景"}
+	adaptive := append([]ParamSpec{
+		{Name: "blockSize", Type: ParamInt, Default: DefaultThresholdOptions.BlockSize, Min: bound(3), Description: "邻域边长, 奇数"},
+		{Name: "c", Type: ParamFloat, Default: DefaultThresholdOptions.C, Description: "从邻域均值中减去的常数"},
+		invert,
+	}, neighborhoodParams()...)
+
+	Register(Operation{
+		Name:     "Threshold - Fixed",
+		Label:    "固定阈值",
+		Category: CategoryThreshold,
+		Params: []ParamSpec{
+			{Name: "threshold", Type: ParamFloat, Default: DefaultThresholdOptions.Value, Min: bound(0), Max: bound(255), Description: "亮度高于该值的像素为前景"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdFixed, false)
+		},
+	})
+	Register(Operation{
+		Name:     "Threshold - Inverted",
+		Label:    "反向阈值",
+		Category: CategoryThreshold,
+		Params: []ParamSpec{
+			{Name: "threshold", Type: ParamFloat, Default: DefaultThresholdOptions.Value, Min: bound(0), Max: bound(255), Description: "亮度不高于该值的像素为前景"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdFixed, true)
+		},
+	})
+	Register(Operation{
+		Name:     "Threshold - Otsu",
+		Label:    "Otsu 阈值",
+		Category: CategoryThreshold,
+		Params:   []ParamSpec{invert},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdOtsu, false)
+		},
+	})
+	Register(Operation{
+		Name:     "Threshold - Triangle",
+		Label:    "三角形阈值",
+		Category: CategoryThreshold,
+		Params:   []ParamSpec{invert},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdTriangle, false)
+		},
+	})
+	Register(Operation{
+		Name:     "Threshold - Adaptive Mean",
+		Label:    "自适应阈值 (均值)",
+		Category: CategoryThreshold,
+		Params:   adaptive,
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdMean, false)
+		},
+	})
+	Register(Operation{
+		Name:     "Threshold - Adaptive Gaussian",
+		Label:    "自适应阈值 (高斯加权)",
+		Category: CategoryThreshold,
+		Params:   adaptive,
+		Apply: func(a *Args) (image.Image, error) {
+			return applyThreshold(a, ThresholdGaussian, false)
+		},
+	})
+}
+
+// applyThreshold 从参数中读取设置, invert 为 true 时总是反转
+func applyThreshold(a *Args, method ThresholdMethod, invert bool) (image.Image, error) {
+	opts := ThresholdOptions{
+		Method:       method,
+		Value:        a.Params.Float("threshold"),
+		Invert:       invert || a.Params.Bool("invert"),
+		BlockSize:    a.Params.Int("blockSize"),
+		C:            a.Params.Float("c"),
+		Neighborhood: neighborhoodOf(a.Params),
+	}
+	mask, _, err := threshold(a.Ctx, a.Images[0], opts)
+	return mask, err
+}
+
+// Threshold splits the luminance of img into a binary mask (see NewMask) and
+// returns the global threshold it used. The adaptive methods compare every
+// pixel with its own neighborhood and return 0.
+func Threshold(img image.Image, opts ThresholdOptions) (*image.Paletted, float64, error) {
+	return threshold(context.Background(), img, opts)
+}
+
+func threshold(ctx context.Context, img image.Image, opts ThresholdOptions) (*image.Paletted, float64, error) {
+	luma := LumaOf(img)
+
+	var t float64
+	switch opts.Method {
+	case ThresholdFixed, "":
+		t = opts.Value
+	case ThresholdOtsu:
+		t = float64(computeHistogram(ctx, luma.Image()).Luminance.otsuThreshold())
+	case ThresholdTriangle:
+		t = float64(computeHistogram(ctx, luma.Image()).Luminance.triangleThreshold())
+	case ThresholdMean, ThresholdGaussian:
+		mask, err := adaptiveThreshold(ctx, luma, opts)
+		return mask, 0, err
+	default:
+		return nil, 0, &ParamError{Param: "method", Reason: fmt.Sprintf("unknown threshold method %q", opts.Method)}
+	}
+	reportMetadata(ctx, "threshold", t)
+
+	mask := NewMask(luma.Rect)
+	err := parallelRows(ctx, luma.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := mask.Pix[y*mask.Stride:]
+			for x, v := range luma.Row(y) {
+				if (float64(v) > t) != opts.Invert {
+					out[x] = 1
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return mask, t, nil
+}
+
+// adaptiveThreshold 像素亮度高于邻域均值减去 C 时为前景
+func adaptiveThreshold(ctx context.Context, luma *Buffer, opts ThresholdOptions) (*image.Paletted, error) {
+	size := opts.BlockSize
+	if size < 3 || size%2 == 0 {
+		return nil, &ParamError{Param: "blockSize", Reason: "must be an odd number of at least 3"}
+	}
+	if size > MaxKernelSize {
+		return nil, &ParamError{Param: "blockSize", Reason: fmt.Sprintf("must be at most %d", MaxKernelSize)}
+	}
+
+	kernel := BoxKernel(size, size)
+	if opts.Method == ThresholdGaussian {
+		// 与 OpenCV 相同, 由窗口大小推出标准差
+		kernel = GaussianKernel(0.3*(float64(size-1)*0.5-1)+0.8, size)
+	}
+	meanImg, err := convolve(subProgress(ctx, 0, 0.8), luma.Image(), kernel, 1, 0, opts.Neighborhood, MethodAuto)
+	if err != nil {
+		return nil, err
+	}
+
+	// 边界为 crop 时均值图像比原图小, 按坐标对齐
+	mean := BufferOf(meanImg)
+	dx, dy := mean.Rect.Min.X-luma.Rect.Min.X, mean.Rect.Min.Y-luma.Rect.Min.Y
+	mask := NewMask(mean.Rect)
+	err = parallelRows(subProgress(ctx, 0.8, 1), mean.Height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			in, out := luma.Row(y + dy)[dx:], mask.Pix[y*mask.Stride:]
+			for x, m := range mean.Row(y) {
+				if (float64(in[x]) > float64(m)-opts.C) != opts.Invert {
+					out[x] = 1
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mask, nil
+}
+
+// otsuThreshold 使两类之间方差最大的阈值, 不高于阈值的像素为一类
+func (h *Histogram) otsuThreshold() int {
+	var total float64
+	for v, n := range h.Counts {
+		total += float64(v * n)
+	}
+
+	best, bestVariance := 0, -1.0
+	var sumBelow float64
+	below := 0
+	for t, n := range h.Counts {
+		below += n
+		above := h.Total - below
+		if below == 0 {
+			continue
+		}
+		if above == 0 {
+			break
+		}
+		sumBelow += float64(t * n)
+		meanBelow := sumBelow / float64(below)
+		meanAbove := (total - sumBelow) / float64(above)
+		variance := float64(below) * float64(above) * (meanBelow - meanAbove) * (meanBelow - meanAbove)
+		if variance > bestVariance {
+			best, bestVariance = t, variance
+		}
+	}
+	return best
+}
+
+// triangleThreshold 连接直方图峰值和较长一侧的端点, 取离这条直线最远的灰度级.
+// 适合前景只占少数像素的图像
+func (h *Histogram) triangleThreshold() int {
+	counts := h.Counts
+	left, right, peak := 0, 0, 0
+	for v, n := range counts {
+		if n > 0 {
+			left = v
+			break
+		}
+	}
+	for v := len(counts) - 1; v > 0; v-- {
+		if counts[v] > 0 {
+			right = v
+			break
+		}
+	}
+	for v, n := range counts {
+		if n > counts[peak] {
+			peak = v
+		}
+	}
+	// 端点向外扩展一级, 使直线落在直方图之外
+	if left > 0 {
+		left--
+	}
+	if right < len(counts)-1 {
+		right++
+	}
+
+	// 较长的一侧在峰值右边时翻转直方图, 下面只处理左侧
+	flipped := peak-left < right-peak
+	if flipped {
+		slices.Reverse(counts[:])
+		left, peak = len(counts)-1-right, len(counts)-1-peak
+	}
+
+	t := left
+	a, b := float64(counts[peak]), float64(left-peak)
+	maxDistance := 0.0
+	for v := left + 1; v <= peak; v++ {
+		// 到直线的距离, 省略了相同的分母
+		if d := a*float64(v) + b*float64(counts[v]); d > maxDistance {
+			t, maxDistance = v, d
+		}
+	}
+	t--
+	if flipped {
+		t = len(counts) - 1 - t
+	}
+	return max(t, 0)
+}
@@ -0,0 +1,82 @@
+package algorithms
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// bimodalImage 10x10 的灰度图像: 90 个像素为 30 (背景), 最后一行的 10 个像素为 200 (前景)
+func bimodalImage() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 10, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 30
+		if i >= 90 {
+			img.Pix[i] = 200
+		}
+	}
+	return img
+}
+
+func TestThresholdMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		want float64
+	}{
+		// Otsu: 30 和 200 之间的任何阈值方差都相同, 取最小的 30
+		{"Threshold - Otsu", 30},
+		// 三角法: 峰值 30 与右端点 201 连线, 最远的空灰度级在峰值右侧, 再向峰值退一级
+		{"Threshold - Triangle", 32},
+		{"Threshold - Fixed", 127},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, ok := Lookup(tt.name)
+			if !ok {
+				t.Fatalf("%s is not registered", tt.name)
+			}
+			ctx, metadata := WithMetadata(context.Background())
+			result, err := op.RunContext(ctx, []image.Image{bimodalImage()}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := metadata["threshold"]; got != tt.want {
+				t.Fatalf("threshold = %v, want %v", got, tt.want)
+			}
+			assertMask(t, result, func(i int) bool { return i >= 90 })
+		})
+	}
+}
+
+func TestThresholdInverted(t *testing.T) {
+	result, err := Apply("Threshold - Inverted", []image.Image{bimodalImage()}, Params{"threshold": 100.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMask(t, result, func(i int) bool { return i < 90 })
+}
+
+// assertMask 检查 img 是两色的调色板掩码, 第 i 个像素为前景当且仅当 foreground(i)
+func assertMask(t *testing.T, img image.Image, foreground func(i int) bool) {
+	t.Helper()
+	mask, ok := img.(*image.Paletted)
+	if !ok {
+		t.Fatalf("result is %T, want *image.Paletted", img)
+	}
+	if len(mask.Palette) != 2 || mask.Palette[0] != (color.Gray{Y: 0}) || mask.Palette[1] != (color.Gray{Y: 255}) {
+		t.Fatalf("palette = %v, want black and white", mask.Palette)
+	}
+	if !IsMask(mask) {
+		t.Fatal("IsMask is false")
+	}
+	for i, v := range mask.Pix {
+		want := uint8(0)
+		if foreground(i) {
+			want = 1
+		}
+		if v != want {
+			t.Fatalf("pixel %d = %d, want %d", i, v, want)
+		}
+	}
+}
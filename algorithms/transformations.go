@@ -1,83 +1,98 @@
 package algorithms
 
 import (
-	"bytes"
-	"encoding/base64"
+	"context"
 	"image"
-	"image/color"
-	"image/jpeg"
 	"math"
-	"math/rand"
-	"mime/multipart"
+	"math/rand/v2"
 )
 
-// GeneralTransformation applies the specified transformation on the image.
-func GeneralTransformation(file multipart.File, transformationType string, c float64, gamma float64) (string, error) {
-	img, err := decodeImage(file)
-	if err != nil {
-		return "", err
-	}
-
-	bounds := img.Bounds()
-	transformedImg := image.NewRGBA(bounds)
-
-	switch transformationType {
-	case "Logarithmic":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				rF := float64(r >> 8)
-				gF := float64(g >> 8)
-				bF := float64(b >> 8)
-
-				newR := uint8(c * math.Log(1+rF))
-				newG := uint8(c * math.Log(1+gF))
-				newB := uint8(c * math.Log(1+bF))
+func init() {
+	Register(Operation{
+		Name:     "Logarithmic Transformation",
+		Label:    "对数变换",
+		Category: CategoryTransformation,
+		Params: []ParamSpec{
+			{Name: "c", Type: ParamFloat, Required: true, Aliases: []string{"param"}, Description: "s = c * log(1 + r), 255 / ln(256) ≈ 46 时把 0-255 映射到 0-255"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], logLUT(a.Params.Float("c")))
+		},
+	})
+	Register(Operation{
+		Name:     "Power Law",
+		Label:    "幂律变换",
+		Category: CategoryTransformation,
+		Params: []ParamSpec{
+			{Name: "c", Type: ParamFloat, Required: true, Aliases: []string{"param"}, Description: "s = c * r ^ gamma"},
+			{Name: "gamma", Type: ParamFloat, Default: 1.0, Min: bound(0), Description: "指数; 把 0-255 映射到 0-255 时 c 取 255 ^ (1 - gamma)"},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], powerLawLUT(a.Params.Float("c"), a.Params.Float("gamma")))
+		},
+	})
+	Register(Operation{
+		Name:     "Random LUT",
+		Label:    "随机 LUT",
+		Category: CategoryTransformation,
+		Params:   []ParamSpec{seedParam()},
+		Apply: func(a *Args) (image.Image, error) {
+			return applyLUT(a.Ctx, a.Images[0], randomLUT(seedOf(a.Ctx, a.Params)))
+		},
+	})
+}
 
-				transformedImg.Set(x, y, color.RGBA{newR, newG, newB, uint8(a >> 8)})
-			}
-		}
+// LogTransform applies s = c * log(1 + r) to every color channel.
+func LogTransform(img image.Image, c float64) image.Image {
+	result, _ := applyLUT(context.Background(), img, logLUT(c))
+	return result
+}
 
-	case "Power Law":
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				rF := float64(r >> 8)
-				gF := float64(g >> 8)
-				bF := float64(b >> 8)
+// PowerLaw applies s = c * r ^ gamma to every color channel, with r in
+// 0-255. Results are clamped to 0-255.
+func PowerLaw(img image.Image, c float64, gamma float64) image.Image {
+	result, _ := applyLUT(context.Background(), img, powerLawLUT(c, gamma))
+	return result
+}
 
-				newR := uint8(c * math.Pow(rF, gamma))
-				newG := uint8(c * math.Pow(gF, gamma))
-				newB := uint8(c * math.Pow(bF, gamma))
+// RandomLUT maps every color channel through a randomly generated lookup table.
+func RandomLUT(img image.Image) image.Image {
+	return RandomLUTSeed(img, rand.Int64())
+}
 
-				transformedImg.Set(x, y, color.RGBA{newR, newG, newB, uint8(a >> 8)})
-			}
-		}
+// RandomLUTSeed is like RandomLUT, but the same seed always generates the
+// same table.
+func RandomLUTSeed(img image.Image, seed int64) image.Image {
+	result, _ := applyLUT(context.Background(), img, randomLUT(seed))
+	return result
+}
 
-	case "Random LUT":
-		lut := make([]uint8, 256)
-		for i := 0; i < 256; i++ {
-			lut[i] = uint8(rand.Intn(256))
-		}
+func logLUT(c float64) *[256]uint8 {
+	return lutOf(func(v float64) float64 {
+		return c * math.Log(1+v)
+	})
+}
 
-		for y := 0; y < bounds.Dy(); y++ {
-			for x := 0; x < bounds.Dx(); x++ {
-				r, g, b, a := img.At(x, y).RGBA()
-				rF := lut[r>>8]
-				gF := lut[g>>8]
-				bF := lut[b>>8]
+func powerLawLUT(c float64, gamma float64) *[256]uint8 {
+	return lutOf(func(v float64) float64 {
+		return c * math.Pow(v, gamma)
+	})
+}
 
-				transformedImg.Set(x, y, color.RGBA{rF, gF, bF, uint8(a >> 8)})
-			}
-		}
+func randomLUT(seed int64) *[256]uint8 {
+	var lut [256]uint8
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	for i := 0; i < 256; i++ {
+		lut[i] = uint8(rng.IntN(256))
 	}
+	return &lut
+}
 
-	var buf bytes.Buffer
-	jpeg.Encode(&buf, transformedImg, nil)
-	if err != nil {
-		return "", err
+// lutOf 由 fn 生成查找表, 结果限制在 0-255
+func lutOf(fn func(v float64) float64) *[256]uint8 {
+	var lut [256]uint8
+	for i := range lut {
+		lut[i] = uint8(clamp(int(fn(float64(i))), 0, 255))
 	}
-
-	base64Image := base64.StdEncoding.EncodeToString(buf.Bytes())
-	return base64Image, nil
+	return &lut
 }
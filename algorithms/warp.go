@@ -0,0 +1,321 @@
+package algorithms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Transform is a 3x3 projective transform of pixel coordinates, where
+// pixel centers lie on integer coordinates. An affine transform has
+// {0, 0, 1} as its last row.
+type Transform [3][3]float64
+
+// Identity is the transform that leaves every point in place.
+var Identity = Transform{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+var errDegenerate = errors.New("the points do not determine a transform, three of them may lie on one line")
+
+func init() {
+	Register(Operation{
+		Name:     "Warp",
+		Label:    "仿射/透视变换",
+		Category: CategoryGeometry,
+		Params: []ParamSpec{
+			{Name: "matrix", Type: ParamKernel, Description: "把原图坐标映射到结果坐标的 2x3 仿射矩阵或 3x3 透视矩阵, JSON 二维数组"},
+			{Name: "srcPoints", Type: ParamPoints, Description: "不指定 matrix 时, 原图中的控制点, 例如 [[x1,y1],[x2,y2],...]"},
+			{Name: "dstPoints", Type: ParamPoints, Description: "与 srcPoints 一一对应的结果图像中的点; 3 对点求仿射变换, 4 对及以上求透视变换"},
+			{Name: "width", Type: ParamInt, Default: 0, Min: bound(0), Description: "结果宽度, 0 表示与原图相同"},
+			{Name: "height", Type: ParamInt, Default: 0, Min: bound(0), Description: "结果高度, 0 表示与原图相同"},
+			interpolationParam(),
+			{Name: "border", Type: ParamEnum, Options: samplingBorderModes, Default: string(BorderConstant), Description: "原图以外区域的取值方式", Advanced: true},
+			{Name: "borderValue", Type: ParamInt, Default: 0, Min: bound(0), Max: bound(255), Description: "constant 模式的填充值", Advanced: true},
+		},
+		Apply: func(a *Args) (image.Image, error) {
+			t, err := transformOf(a.Params)
+			if err != nil {
+				return nil, err
+			}
+			if !a.Params.Has("matrix") {
+				// 由控制点求出的矩阵, 可以在之后的请求中直接使用
+				if t.IsAffine() {
+					reportMetadata(a.Ctx, "matrix", t[:2])
+				} else {
+					reportMetadata(a.Ctx, "matrix", t)
+				}
+			}
+			return warp(a.Ctx, a.Images[0], t, a.Params.Int("width"), a.Params.Int("height"), Interpolation(a.Params.String("interpolation")),
+				BorderMode(a.Params.String("border")), uint8(a.Params.Int("borderValue")))
+		},
+	})
+}
+
+// transformOf 从 matrix 参数或控制点求出变换
+func transformOf(p Params) (Transform, error) {
+	if m := p.Kernel("matrix"); m != nil {
+		if p.Has("srcPoints") || p.Has("dstPoints") {
+			return Transform{}, &ParamError{Param: "matrix", Reason: "can not be used together with srcPoints and dstPoints"}
+		}
+		if m.Width != 3 || (m.Height != 2 && m.Height != 3) {
+			return Transform{}, &ParamError{Param: "matrix", Reason: fmt.Sprintf("must be 2x3 or 3x3, got %dx%d", m.Height, m.Width)}
+		}
+		t := Identity
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < 3; x++ {
+				t[y][x] = m.At(x, y)
+			}
+		}
+		return t, nil
+	}
+
+	src, dst := p.Points("srcPoints"), p.Points("dstPoints")
+	if src == nil || dst == nil {
+		return Transform{}, &ParamError{Param: "matrix", Reason: "either matrix or both srcPoints and dstPoints are required"}
+	}
+	if len(src) != len(dst) {
+		return Transform{}, &ParamError{Param: "dstPoints", Reason: fmt.Sprintf("has %d points, srcPoints has %d", len(dst), len(src))}
+	}
+
+	var t Transform
+	var err error
+	switch n := len(src); {
+	case n < 3:
+		return Transform{}, &ParamError{Param: "srcPoints", Reason: "at least 3 points are required"}
+	case n == 3:
+		t, err = AffineFromPoints(src, dst)
+	default:
+		t, err = PerspectiveFromPoints(src, dst)
+	}
+	if err != nil {
+		return Transform{}, &ParamError{Param: "srcPoints", Reason: err.Error()}
+	}
+	return t, nil
+}
+
+// ParsePoints parses a JSON list of points such as [[10, 20], [30, 40]].
+// Every point must have exactly two coordinates.
+func ParsePoints(s string) ([][2]float64, error) {
+	var rows [][]float64
+	if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &rows); err != nil {
+		return nil, fmt.Errorf("points must be a JSON array of [x, y] pairs")
+	}
+	points := make([][2]float64, len(rows))
+	for i, row := range rows {
+		if len(row) != 2 {
+			return nil, fmt.Errorf("point %d has %d values, every point must be [x, y]", i, len(row))
+		}
+		points[i] = [2]float64{row[0], row[1]}
+	}
+	return points, nil
+}
+
+// IsAffine reports whether t keeps parallel lines parallel.
+func (t Transform) IsAffine() bool {
+	return t[2] == [3]float64{0, 0, 1}
+}
+
+// Apply maps the point (x, y). Points mapped to infinity return ok = false.
+func (t Transform) Apply(x, y float64) (tx, ty float64, ok bool) {
+	w := t[2][0]*x + t[2][1]*y + t[2][2]
+	if w == 0 {
+		return 0, 0, false
+	}
+	return (t[0][0]*x + t[0][1]*y + t[0][2]) / w, (t[1][0]*x + t[1][1]*y + t[1][2]) / w, true
+}
+
+// Inverse returns the transform that undoes t.
+func (t Transform) Inverse() (Transform, error) {
+	// 伴随矩阵除以行列式
+	var inv Transform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			inv[i][j] = t[a][c]*t[b][d] - t[a][d]*t[b][c]
+		}
+	}
+	det := t[0][0]*inv[0][0] + t[0][1]*inv[1][0] + t[0][2]*inv[2][0]
+	if math.Abs(det) < 1e-12 {
+		return Transform{}, errors.New("the transform is not invertible")
+	}
+	for i := range inv {
+		for j := range inv[i] {
+			inv[i][j] /= det
+		}
+	}
+	return inv, nil
+}
+
+// AffineFromPoints solves the affine transform that maps the three points
+// of src to the three points of dst.
+func AffineFromPoints(src, dst [][2]float64) (Transform, error) {
+	if len(src) != 3 || len(dst) != 3 {
+		return Transform{}, errors.New("an affine transform needs exactly 3 point pairs")
+	}
+	// 每对点给出两个方程: x' = a x + b y + c, y' = d x + e y + f
+	a := make([][]float64, 6)
+	b := make([]float64, 6)
+	for i := range src {
+		x, y := src[i][0], src[i][1]
+		a[2*i] = []float64{x, y, 1, 0, 0, 0}
+		a[2*i+1] = []float64{0, 0, 0, x, y, 1}
+		b[2*i], b[2*i+1] = dst[i][0], dst[i][1]
+	}
+	v, ok := solveLinear(a, b)
+	if !ok {
+		return Transform{}, errDegenerate
+	}
+	return Transform{{v[0], v[1], v[2]}, {v[3], v[4], v[5]}, {0, 0, 1}}, nil
+}
+
+// PerspectiveFromPoints solves the homography that maps the points of src
+// to the points of dst. Four pairs give an exact solution; more pairs are
+// fitted in the least squares sense.
+func PerspectiveFromPoints(src, dst [][2]float64) (Transform, error) {
+	if len(src) < 4 || len(src) != len(dst) {
+		return Transform{}, errors.New("a perspective transform needs at least 4 point pairs")
+	}
+	// 固定 h33 = 1, 每对点给出两个线性方程:
+	// x' = (h11 x + h12 y + h13) / (h31 x + h32 y + 1), y' 同理
+	a := make([][]float64, 0, 2*len(src))
+	b := make([]float64, 0, 2*len(src))
+	for i := range src {
+		x, y, u, v := src[i][0], src[i][1], dst[i][0], dst[i][1]
+		a = append(a,
+			[]float64{x, y, 1, 0, 0, 0, -u * x, -u * y},
+			[]float64{0, 0, 0, x, y, 1, -v * x, -v * y})
+		b = append(b, u, v)
+	}
+	if len(src) > 4 {
+		// 最小二乘: 解法方程 AᵀA h = Aᵀb
+		a, b = normalEquations(a, b)
+	}
+	h, ok := solveLinear(a, b)
+	if !ok {
+		return Transform{}, errDegenerate
+	}
+	return Transform{{h[0], h[1], h[2]}, {h[3], h[4], h[5]}, {h[6], h[7], 1}}, nil
+}
+
+// normalEquations 返回 AᵀA 和 Aᵀb
+func normalEquations(a [][]float64, b []float64) ([][]float64, []float64) {
+	n := len(a[0])
+	ata := make([][]float64, n)
+	atb := make([]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n)
+		for k := range a {
+			for j := range ata[i] {
+				ata[i][j] += a[k][i] * a[k][j]
+			}
+			atb[i] += a[k][i] * b[k]
+		}
+	}
+	return ata, atb
+}
+
+// solveLinear 用列主元高斯消元解方阵方程 a x = b, a 和 b 会被修改. 奇异时返回 false
+func solveLinear(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	// 主元相对于最大元素过小时视为奇异
+	var scale float64
+	for _, row := range a {
+		for _, v := range row {
+			scale = max(scale, math.Abs(v))
+		}
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) <= 1e-12*scale {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for c := r + 1; c < n; c++ {
+			sum -= a[r][c] * x[c]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x, true
+}
+
+// Warp maps img through t into a width x height image (0 keeps the size of
+// img). Every output pixel takes the interpolated value at its preimage
+// under t; areas that come from outside img are black.
+func Warp(img image.Image, t Transform, width, height int, interp Interpolation) (image.Image, error) {
+	return warp(context.Background(), img, t, width, height, interp, BorderConstant, 0)
+}
+
+func warp(ctx context.Context, img image.Image, t Transform, width, height int, interp Interpolation, border BorderMode, value uint8) (image.Image, error) {
+	// 矩阵乘以负数表示同一个变换; 统一为原图中心的齐次坐标 w > 0,
+	// 这样逆变换后 w <= 0 的点一定不在原图一侧
+	b := img.Bounds()
+	if cx, cy := float64(b.Dx()-1)/2, float64(b.Dy()-1)/2; t[2][0]*cx+t[2][1]*cy+t[2][2] < 0 {
+		for i := range t {
+			for j := range t[i] {
+				t[i][j] = -t[i][j]
+			}
+		}
+	}
+	inv, err := t.Inverse()
+	if err != nil {
+		return nil, &ParamError{Param: "matrix", Reason: err.Error()}
+	}
+	src := pixelsOf(img)
+	if width == 0 {
+		width = src.Width
+	}
+	if height == 0 {
+		height = src.Height
+	}
+	if err := checkOutputSize("width", width, height); err != nil {
+		return nil, err
+	}
+	s, err := newSampler(src, interp, border, value)
+	if err != nil {
+		return nil, err
+	}
+
+	dst := NewBuffer(image.Rect(0, 0, width, height), src.Channels, Interleaved, src.Model)
+	ch := src.Channels
+	err = parallelRows(ctx, height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			out := dst.Row(y)
+			for x := 0; x < width; x++ {
+				px := out[x*ch : (x+1)*ch]
+				// 透视变换中位于消失线另一侧的点没有原像, 按原图以外处理
+				w := inv[2][0]*float64(x) + inv[2][1]*float64(y) + inv[2][2]
+				if w <= 0 {
+					copy(px, s.fill)
+					continue
+				}
+				sx, sy, _ := inv.Apply(float64(x), float64(y))
+				s.at(sx, sy, px)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultOf(img, dst), nil
+}
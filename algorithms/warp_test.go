@@ -0,0 +1,166 @@
+package algorithms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"math"
+	"testing"
+)
+
+// 已知的仿射和透视变换, 用于生成对应点
+var (
+	testAffine      = Transform{{1.2, -0.3, 5}, {0.4, 0.9, -2}, {0, 0, 1}}
+	testPerspective = Transform{{0.9, 0.1, 3}, {-0.2, 1.1, 4}, {0.001, 0.002, 1}}
+)
+
+// mapPoints 返回 src 经过 t 变换后的点
+func mapPoints(t *testing.T, tr Transform, src [][2]float64) [][2]float64 {
+	t.Helper()
+	dst := make([][2]float64, len(src))
+	for i, p := range src {
+		x, y, ok := tr.Apply(p[0], p[1])
+		if !ok {
+			t.Fatalf("point %v maps to infinity", p)
+		}
+		dst[i] = [2]float64{x, y}
+	}
+	return dst
+}
+
+// assertTransform 检查 got 与 want 的每个元素相差不超过 tol
+func assertTransform(t *testing.T, got, want Transform, tol float64) {
+	t.Helper()
+	for i := range got {
+		for j := range got[i] {
+			if math.Abs(got[i][j]-want[i][j]) > tol {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestAffineFromPoints(t *testing.T) {
+	src := [][2]float64{{0, 0}, {10, 0}, {0, 10}}
+	got, err := AffineFromPoints(src, mapPoints(t, testAffine, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTransform(t, got, testAffine, 1e-9)
+}
+
+func TestPerspectiveFromPoints(t *testing.T) {
+	src := [][2]float64{{0, 0}, {100, 0}, {100, 80}, {0, 80}}
+	got, err := PerspectiveFromPoints(src, mapPoints(t, testPerspective, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTransform(t, got, testPerspective, 1e-9)
+}
+
+func TestPerspectiveLeastSquares(t *testing.T) {
+	src := [][2]float64{{0, 0}, {100, 0}, {100, 80}, {0, 80}, {50, 40}, {20, 70}, {90, 10}, {60, 60}}
+	dst := mapPoints(t, testPerspective, src)
+
+	// 没有误差时最小二乘得到精确解
+	got, err := PerspectiveFromPoints(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertTransform(t, got, testPerspective, 1e-6)
+
+	// 交替的小误差相互抵消, 每个点的残差都应小于误差本身
+	for i := range dst {
+		d := 0.3
+		if i%2 == 1 {
+			d = -d
+		}
+		dst[i][0] += d
+		dst[i][1] -= d
+	}
+	got, err = PerspectiveFromPoints(src, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fitted := mapPoints(t, got, src)
+	for i := range src {
+		if dx, dy := fitted[i][0]-dst[i][0], fitted[i][1]-dst[i][1]; math.Hypot(dx, dy) > 0.6 {
+			t.Errorf("point %d: residual (%.3f, %.3f)", i, dx, dy)
+		}
+	}
+}
+
+func TestDegeneratePoints(t *testing.T) {
+	collinear := [][2]float64{{0, 0}, {1, 1}, {2, 2}}
+	if _, err := AffineFromPoints(collinear, [][2]float64{{0, 0}, {1, 0}, {0, 1}}); !errors.Is(err, errDegenerate) {
+		t.Errorf("affine: got %v, want errDegenerate", err)
+	}
+	collinear = append(collinear, [2]float64{3, 3})
+	if _, err := PerspectiveFromPoints(collinear, [][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}}); !errors.Is(err, errDegenerate) {
+		t.Errorf("perspective: got %v, want errDegenerate", err)
+	}
+}
+
+func TestInverse(t *testing.T) {
+	for _, tr := range []Transform{Identity, testAffine, testPerspective} {
+		inv, err := tr.Inverse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var product Transform
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				for k := 0; k < 3; k++ {
+					product[i][j] += tr[i][k] * inv[k][j]
+				}
+			}
+		}
+		assertTransform(t, product, Identity, 1e-9)
+	}
+
+	// 第三行是前两行之和
+	singular := Transform{{1, 2, 3}, {4, 5, 6}, {5, 7, 9}}
+	if _, err := singular.Inverse(); err == nil {
+		t.Error("inverted a singular matrix")
+	}
+}
+
+func TestWarpNegatedMatrix(t *testing.T) {
+	img := testImages()["gray"]
+	shift := Transform{{1, 0, 2}, {0, 1, 1}, {0, 0, 1}}
+	var negated Transform
+	for i := range shift {
+		for j := range shift[i] {
+			negated[i][j] = -shift[i][j]
+		}
+	}
+
+	got, err := warp(context.Background(), img, shift, 0, 0, InterpNearest, BorderConstant, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 乘以 -1 表示同一个变换, w < 0 的矩阵不能把所有像素都当作原图以外
+	same, err := warp(context.Background(), img, negated, 0, 0, InterpNearest, BorderConstant, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pixelsOf(got).Pix, pixelsOf(same).Pix) {
+		t.Fatal("negating the matrix changes the result")
+	}
+
+	// 平移 (2, 1): 结果的 (x+2, y+1) 是原图的 (x, y), 左上方空出的区域为 0
+	gray, out := img.(*image.Gray), got.(*image.Gray)
+	b := gray.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			want := uint8(0)
+			if x >= 2 && y >= 1 {
+				want = gray.GrayAt(x-2, y-1).Y
+			}
+			if v := out.GrayAt(x, y).Y; v != want {
+				t.Fatalf("(%d, %d) = %d, want %d", x, y, v, want)
+			}
+		}
+	}
+}
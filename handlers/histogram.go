@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"log"
+	"net/http"
+)
+
+// channelHistogram 单个通道的直方图, cdf 为 0-1 的累积分布
+type channelHistogram struct {
+	Counts []int     `json:"counts"`
+	CDF    []float64 `json:"cdf"`
+	algorithms.HistogramStats
+}
+
+// histogramResponse 是 /imageProcessing/histogram 返回的 JSON, 灰度图像没有颜色通道
+type histogramResponse struct {
+	Width    int                         `json:"width"`
+	Height   int                         `json:"height"`
+	Pixels   int                         `json:"pixels"`
+	Channels map[string]channelHistogram `json:"channels"`
+}
+
+// Histogram 返回上传图像每个颜色通道和亮度的直方图、累积分布和统计值
+func Histogram(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		log.Println("Error parsing multipart form:", err)
+		http.Error(w, "ParseMultipartForm", http.StatusBadRequest)
+		return
+	}
+	img, err := readImage(r, "image")
+	if err != nil {
+		log.Println("Error reading image:", err)
+		http.Error(w, "Invalid image upload", http.StatusBadRequest)
+		return
+	}
+
+	h := algorithms.ComputeHistogram(img)
+	bounds := img.Bounds()
+	response := histogramResponse{
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		Pixels:   h.Luminance.Total,
+		Channels: map[string]channelHistogram{"luminance": newChannelHistogram(h.Luminance)},
+	}
+	if h.Red != nil {
+		response.Channels["red"] = newChannelHistogram(h.Red)
+		response.Channels["green"] = newChannelHistogram(h.Green)
+		response.Channels["blue"] = newChannelHistogram(h.Blue)
+	}
+	writeJSON(w, response)
+}
+
+func newChannelHistogram(h *algorithms.Histogram) channelHistogram {
+	return channelHistogram{
+		Counts:         h.Counts[:],
+		CDF:            h.CDF(),
+		HistogramStats: h.Stats(),
+	}
+}
@@ -2,261 +2,196 @@ package handlers
 
 import (
 	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"strconv"
+	"time"
 )
 
+// 上传表单的大小上限
+const maxUploadSize = 10 << 20
+
+// 第 n 张输入图像对应的表单字段
+var imageFields = []string{"image", "secondImage"}
+
+// CategoryRoutes 每个算子分类对应的处理接口
+var CategoryRoutes = map[string]string{
+	algorithms.CategoryMixed:          "/imageProcessing/process",
+	algorithms.CategoryArithmetic:     "/imageProcessing/process/arithmeticOperations",
+	algorithms.CategoryBitwise:        "/imageProcessing/process/bitOperations",
+	algorithms.CategoryConvolution:    "/imageProcessing/process/convolution",
+	algorithms.CategoryTransformation: "/imageProcessing/process/transformations",
+	algorithms.CategoryThreshold:      "/imageProcessing/process/threshold",
+	algorithms.CategoryMorphology:     "/imageProcessing/process/morphology",
+	algorithms.CategoryGeometry:       "/imageProcessing/process/geometry",
+}
+
 // ProcessMixedAlgorithms 处理混合算法 (Rescaling, Negative, Shift&Rescale, etc.)
 func ProcessMixedAlgorithms(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
-		return
-	}
-	algorithm := r.FormValue("algorithm")
-	log.Printf("Algorithm selected: %s", algorithm)
+	processCategory(w, r, algorithms.CategoryMixed)
+}
 
-	file, header, err := r.FormFile("image")
-	if err != nil {
-		log.Printf("Error reading file: %v", err)
-		http.Error(w, "Invalid image upload", http.StatusBadRequest)
-		return
-	}
-	log.Printf("Uploaded file: %s, size: %d, header: %v", header.Filename, header.Size, header.Header)
-	defer func(file multipart.File) {
-		err := file.Close()
-		if err != nil {
+// ProcessArithmeticOperations 处理算术运算 (Addition, Substraction, etc.)
+func ProcessArithmeticOperations(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryArithmetic)
+}
 
-		}
-	}(file)
+// ProcessBitOperations 处理位运算
+func ProcessBitOperations(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryBitwise)
+}
 
-	var base64Image string
-	switch algorithm {
-	case "Rescaling":
-		scalingFactor, err := strconv.ParseFloat(r.FormValue("scalingFactor"), 64)
-		if err != nil {
-			http.Error(w, "Invalid scaling factor", http.StatusBadRequest)
-			return
-		}
-		base64Image, err = algorithms.MixedAlgorithms(file, algorithm, scalingFactor, 0, 0)
-	case "Negative":
-		base64Image, err = algorithms.MixedAlgorithms(file, algorithm, 0, 0, 0)
-		if err != nil {
-			http.Error(w, "Error processing image: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+// ProcessConvolution 处理卷积操作
+func ProcessConvolution(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryConvolution)
+}
 
-	case "Shift&Rescale":
-		scalingFactor, err := strconv.ParseFloat(r.FormValue("scalingFactor"), 64)
-		shiftingValue, err := strconv.ParseFloat(r.FormValue("shiftingValue"), 64)
-		if err != nil {
-			http.Error(w, "Invalid shift value", http.StatusBadRequest)
-			return
-		}
-		base64Image, err = algorithms.MixedAlgorithms(file, algorithm, scalingFactor, shiftingValue, 0)
-	case "Bit Plane Slicing":
-		n, err := strconv.Atoi(r.FormValue("nBit"))
-		if err != nil {
-			http.Error(w, "invalid number of bit plane", http.StatusBadRequest)
-			return
-		}
-		base64Image, err = algorithms.MixedAlgorithms(file, algorithm, 0, 0, n)
-	case "Salt&Pepper noise":
-		base64Image, err = algorithms.MixedAlgorithms(file, algorithm, 0, 0, 0)
-	default:
-		http.Error(w, "Unknown algorithm", http.StatusBadRequest)
-		return
-	}
+// ProcessTransformations 处理图像变换 (Logarithmic Transformation, Power Law, etc.)
+func ProcessTransformations(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryTransformation)
+}
 
-	if err != nil {
-		http.Error(w, "Error processing image", http.StatusInternalServerError)
-		return
-	}
+// ProcessThreshold 处理阈值分割 (Fixed, Otsu, Adaptive, etc.), 返回二值掩码
+func ProcessThreshold(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryThreshold)
+}
 
-	// 返回 base64 编码的图像
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(base64Image))
+// ProcessMorphology 处理形态学运算 (Erode, Dilate, Open, Close, etc.)
+func ProcessMorphology(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryMorphology)
+}
 
-	//http.ServeFile(w, r, base64Image)
+// ProcessGeometry 处理几何变换 (Resize, Rotate, Flip, Transpose, Crop)
+func ProcessGeometry(w http.ResponseWriter, r *http.Request) {
+	processCategory(w, r, algorithms.CategoryGeometry)
 }
 
-// ProcessArithmeticOperations 处理算术运算 (Addition, Substraction, etc.)
-func ProcessArithmeticOperations(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
-		log.Println("Error parsing multipart form:", err)
-		http.Error(w, "ParseMultipartForm", http.StatusBadRequest)
-		return
+// ListOperations 返回所有已注册的算子及其参数说明, 供前端生成菜单
+func ListOperations(w http.ResponseWriter, r *http.Request) {
+	type operationInfo struct {
+		*algorithms.Operation
+		Endpoint string `json:"endpoint"`
 	}
 
-	file1, _, err := r.FormFile("image")
-	if err != nil {
-		log.Println("Error uploading first image:", err)
-		http.Error(w, "Invalid image upload", http.StatusBadRequest)
-		return
+	var ops []operationInfo
+	for _, op := range algorithms.Operations(r.URL.Query().Get("category")) {
+		ops = append(ops, operationInfo{Operation: op, Endpoint: CategoryRoutes[op.Category]})
 	}
-	defer file1.Close()
 
-	file2, _, err := r.FormFile("secondImage")
-	if err != nil {
-		log.Println("Error uploading second image:", err)
-		http.Error(w, "Invalid second image upload", http.StatusBadRequest)
-		return
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(ops); err != nil {
+		log.Println("Error encoding operations:", err)
 	}
-	defer file2.Close()
-
-	algorithm := r.FormValue("algorithm")
-	log.Println("Selected algorithm:", algorithm)
+}
 
-	var base64Image string
-	switch algorithm {
-	case "Addition":
-		base64Image, err = algorithms.ArithmeticOperations(file1, file2, "Addition")
-	case "Substraction":
-		base64Image, err = algorithms.ArithmeticOperations(file1, file2, "Substraction")
-	case "Multiplication":
-		base64Image, err = algorithms.ArithmeticOperations(file1, file2, "Multiplication")
-	case "Division":
-		base64Image, err = algorithms.ArithmeticOperations(file1, file2, "Division")
-	default:
-		log.Println("Unknown arithmetic operation:", algorithm)
-		http.Error(w, "Unknown arithmetic operation", http.StatusBadRequest)
-		return
-	}
+// processRequest 已解析的单个算子请求
+type processRequest struct {
+	op     *algorithms.Operation
+	params algorithms.Params
+	images []image.Image
+	output outputOptions
+}
 
+// processCategory 通用处理流程: 查找算子, 解析参数和上传图像, 调用 algorithms 包后按 outputFormat 和 Accept 头返回结果
+func processCategory(w http.ResponseWriter, r *http.Request, category string) {
+	req, err := parseProcessRequest(r, category)
 	if err != nil {
-		log.Println("Error processing image:", err)
-		http.Error(w, "Error processing image", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// 返回 base64 编码的图像
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(base64Image))
-
-	//http.ServeFile(w, r, processedImagePath)
-}
-
-// ProcessBitOperations 处理位运算
-func ProcessBitOperations(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+	result, err := req.run(r.Context())
 	if err != nil {
+		writeProcessError(w, err)
 		return
 	}
+	writeResult(w, r, result, req.output)
+}
 
-	file1, _, err := r.FormFile("image")
+// parseProcessRequest 解析处理请求的表单, category 为空时接受任意分类的算子.
+// 返回的错误都是客户端错误
+func parseProcessRequest(r *http.Request, category string) (*processRequest, error) {
+	err := r.ParseMultipartForm(maxUploadSize)
 	if err != nil {
-		http.Error(w, "Invalid image upload", http.StatusBadRequest)
-		return
+		log.Println("Error parsing multipart form:", err)
+		return nil, errors.New("ParseMultipartForm")
 	}
-	defer file1.Close()
 
 	algorithm := r.FormValue("algorithm")
+	log.Printf("Algorithm selected: %s", algorithm)
 
-	var base64Image string
-	switch algorithm {
-	case "Bitwise Not":
-		base64Image, err = algorithms.BitOperations(file1, nil, algorithm)
-	case "Bitwise And", "Bitwise Or", "Bitwise Xor":
-		file2, _, err := r.FormFile("secondImage")
-		if err != nil {
-			http.Error(w, "Invalid second image upload", http.StatusBadRequest)
-			return
-		}
-		defer file2.Close()
-
-		base64Image, err = algorithms.BitOperations(file1, file2, algorithm)
-	default:
-		http.Error(w, "Unknown bit operation", http.StatusBadRequest)
-		return
+	op, ok := algorithms.Lookup(algorithm)
+	if !ok || (category != "" && op.Category != category) {
+		return nil, errors.New("Unknown algorithm")
 	}
 
+	req := &processRequest{op: op}
+	req.params, err = op.ParseParams(formLookup(r))
 	if err != nil {
-		http.Error(w, "Error processing image", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	// 返回 base64 编码的图像
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(base64Image))
-
-	//http.ServeFile(w, r, processedImagePath)
-}
-
-// ProcessConvolution 处理卷积操作
-func ProcessConvolution(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+	req.output, err = parseOutputOptions(r)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	file, _, err := r.FormFile("image")
-	if err != nil {
-		http.Error(w, "Invalid image upload", http.StatusBadRequest)
-		return
+	req.images = make([]image.Image, op.Arity)
+	for i := range req.images {
+		req.images[i], err = readImage(r, imageFields[i])
+		if err != nil {
+			log.Printf("Error reading %s: %v", imageFields[i], err)
+			return nil, errors.New("Invalid " + imageFields[i] + " upload")
+		}
 	}
-	defer file.Close()
-
-	algorithm := r.FormValue("algorithm")
+	return req, nil
+}
 
-	var base64Image string
-	base64Image, err = algorithms.Convolution(file, algorithm)
+// run 执行算子并记录耗时和元数据
+func (req *processRequest) run(ctx context.Context) (processResult, error) {
+	start := time.Now()
+	ctx, metadata := algorithms.WithMetadata(ctx)
+	result, err := req.op.RunContext(ctx, req.images, req.params)
 	if err != nil {
-		http.Error(w, "Error processing image", http.StatusInternalServerError)
-		return
+		return processResult{}, err
 	}
-
-	//http.ServeFile(w, r, processedImagePath)
-
-	// 返回 base64 编码的图像
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(base64Image))
+	return processResult{Algorithm: req.op.Name, Image: result, Elapsed: time.Since(start), Metadata: metadata}, nil
 }
 
-// ProcessTransformations 处理图像变换 (Logarithmic Transformation, Power Law, etc.)
-func ProcessTransformations(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
-	if err != nil {
+// writeProcessError 参数错误返回 400, 其余返回 500
+func writeProcessError(w http.ResponseWriter, err error) {
+	log.Println("Error processing image:", err)
+	var paramErr *algorithms.ParamError
+	if errors.As(err, &paramErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	http.Error(w, "Error processing image", http.StatusInternalServerError)
+}
 
-	file, _, err := r.FormFile("image")
+// readImage 读取并解码表单中的上传图像
+func readImage(r *http.Request, field string) (image.Image, error) {
+	file, header, err := r.FormFile(field)
 	if err != nil {
-		http.Error(w, "Invalid image upload", http.StatusBadRequest)
-		return
+		return nil, err
 	}
 	defer file.Close()
+	log.Printf("Uploaded file: %s, size: %d", header.Filename, header.Size)
 
-	algorithm := r.FormValue("algorithm")
-	param, err := strconv.ParseFloat(r.FormValue("param"), 64)
-	if err != nil {
-		http.Error(w, "Invalid parameter", http.StatusBadRequest)
-		return
-	}
-
-	var base64Image string
-	switch algorithm {
-	case "Logarithmic Transformation":
-
-		base64Image, err = algorithms.GeneralTransformation(file, algorithm, param, 0)
-	case "Power Law":
-		base64Image, err = algorithms.GeneralTransformation(file, algorithm, param, 0)
-	case "Random LUT":
-		base64Image, err = algorithms.GeneralTransformation(file, algorithm, 0, 0)
-	default:
-		http.Error(w, "Unknown transformation", http.StatusBadRequest)
-		return
-	}
+	img, _, err := algorithms.Decode(file)
+	return img, err
+}
 
-	if err != nil {
-		http.Error(w, "Error processing image", http.StatusInternalServerError)
-		return
+// formLookup 从表单中读取参数值
+func formLookup(r *http.Request) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		values, ok := r.Form[name]
+		if !ok || len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
 	}
-
-	//http.ServeFile(w, r, processedImagePath)
-
-	// 返回 base64 编码的图像
-	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(base64Image))
 }
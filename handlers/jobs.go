@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"WebAssembly-Based_Image_Processing_Tool/jobs"
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// JobHandlers 异步任务接口: 提交后立即返回任务 ID, 客户端轮询状态并获取结果
+type JobHandlers struct {
+	Queue *jobs.Queue
+}
+
+// Submit 提交任务. 表单与处理接口相同: 有 steps 字段时按流水线执行,
+// 否则执行 algorithm 指定的算子 (任意分类)
+func (h *JobHandlers) Submit(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseMultipartForm(maxUploadSize)
+	if err != nil {
+		log.Println("Error parsing multipart form:", err)
+		http.Error(w, "ParseMultipartForm", http.StatusBadRequest)
+		return
+	}
+
+	var run func(ctx context.Context) (processResult, error)
+	var output outputOptions
+	if r.FormValue("steps") != "" {
+		req, err := parsePipelineRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		output = req.output
+		run = func(ctx context.Context) (processResult, error) {
+			result, _, err := req.run(ctx)
+			return result, err
+		}
+	} else {
+		req, err := parseProcessRequest(r, "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		output = req.output
+		run = req.run
+	}
+
+	job, err := h.Queue.Submit(func(ctx context.Context, progress func(float64)) (*jobs.Result, error) {
+		result, err := run(algorithms.WithProgress(ctx, progress))
+		if err != nil {
+			return nil, err
+		}
+
+		output.forImage(result.Image)
+		var buf bytes.Buffer
+		if err := algorithms.Encode(&buf, result.Image, output.EncodeOptions); err != nil {
+			return nil, err
+		}
+		return &jobs.Result{Data: buf.Bytes(), ContentType: output.Format.ContentType(), Metadata: result.Metadata}, nil
+	})
+	if err != nil {
+		log.Println("Error submitting job:", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
+
+// Status 返回任务状态和进度
+func (h *JobHandlers) Status(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Queue.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, job)
+}
+
+// Result 返回已完成任务的结果图像
+func (h *JobHandlers) Result(w http.ResponseWriter, r *http.Request) {
+	result, job, err := h.Queue.Result(r.PathValue("id"))
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, jobs.ErrNotFinished):
+		writeJSONStatus(w, http.StatusConflict, job)
+		return
+	}
+
+	switch job.Status {
+	case jobs.StatusCancelled:
+		http.Error(w, "job was cancelled", http.StatusGone)
+	case jobs.StatusFailed:
+		http.Error(w, "job failed: "+job.Error, http.StatusInternalServerError)
+	default:
+		setMetadataHeader(w, result.Metadata)
+		w.Header().Set("Content-Type", result.ContentType)
+		w.Write(result.Data)
+	}
+}
+
+// Cancel 取消排队中或运行中的任务
+func (h *JobHandlers) Cancel(w http.ResponseWriter, r *http.Request) {
+	job, err := h.Queue.Cancel(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, job)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptRange 是 Accept 头中的一项, 例如 "image/png;q=0.8"
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept 按 q 值从高到低返回 Accept 头中的媒体类型, q=0 的项被忽略
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+
+	// 相同 q 值时更具体的类型优先
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+	return ranges
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 一条流水线最多包含的步骤数
+const maxPipelineSteps = 32
+
+// pipelineStep 是 steps 字段中的一项, params 的值可以是 JSON 数字、字符串或布尔值
+type pipelineStep struct {
+	Algorithm string                     `json:"algorithm"`
+	Params    map[string]json.RawMessage `json:"params"`
+}
+
+// pipelineEnvelope 是返回中间结果时的 JSON 结构
+type pipelineEnvelope struct {
+	imageEnvelope
+	Steps []imageEnvelope `json:"steps"`
+}
+
+// pipelineRequest 已解析的流水线请求
+type pipelineRequest struct {
+	pipeline     algorithms.Pipeline
+	image        image.Image
+	output       outputOptions
+	intermediate bool
+}
+
+// ProcessPipeline 在内存中依次执行 steps 中的算子, 只在最后编码一次.
+// intermediate=true 时以 JSON 返回每一步的结果
+func ProcessPipeline(w http.ResponseWriter, r *http.Request) {
+	req, err := parsePipelineRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	final, steps, err := req.run(r.Context())
+	if err != nil {
+		writeProcessError(w, err)
+		return
+	}
+
+	if !req.intermediate {
+		writeResult(w, r, final, req.output)
+		return
+	}
+
+	// 中间结果只能以 JSON 返回
+	envelope, err := newEnvelope(final, req.output)
+	if err != nil {
+		log.Println("Error encoding image:", err)
+		http.Error(w, "Error encoding image", http.StatusInternalServerError)
+		return
+	}
+	response := pipelineEnvelope{imageEnvelope: envelope}
+	for _, step := range steps {
+		stepEnvelope, err := newEnvelope(step, req.output)
+		if err != nil {
+			log.Println("Error encoding image:", err)
+			http.Error(w, "Error encoding image", http.StatusInternalServerError)
+			return
+		}
+		response.Steps = append(response.Steps, stepEnvelope)
+	}
+	writeJSON(w, response)
+}
+
+// parsePipelineRequest 解析流水线请求的表单, 返回的错误都是客户端错误
+func parsePipelineRequest(r *http.Request) (*pipelineRequest, error) {
+	err := r.ParseMultipartForm(maxUploadSize)
+	if err != nil {
+		log.Println("Error parsing multipart form:", err)
+		return nil, errors.New("ParseMultipartForm")
+	}
+
+	req := &pipelineRequest{}
+	req.pipeline.Steps, err = parseSteps(r.FormValue("steps"))
+	if err != nil {
+		return nil, err
+	}
+
+	req.output, err = parseOutputOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if v := r.FormValue("intermediate"); v != "" {
+		req.intermediate, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("Invalid intermediate flag")
+		}
+	}
+
+	req.image, err = readImage(r, "image")
+	if err != nil {
+		log.Println("Error reading image:", err)
+		return nil, errors.New("Invalid image upload")
+	}
+
+	if len(r.MultipartForm.File["secondImage"]) > 0 {
+		req.pipeline.Second, err = readImage(r, "secondImage")
+		if err != nil {
+			log.Println("Error reading second image:", err)
+			return nil, errors.New("Invalid secondImage upload")
+		}
+	}
+	if err := req.pipeline.Validate(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// run 执行流水线, intermediate 为 true 时同时返回每一步的结果
+func (req *pipelineRequest) run(ctx context.Context) (processResult, []processResult, error) {
+	var steps []processResult
+	start := time.Now()
+	ctx, metadata := algorithms.WithMetadata(ctx)
+	result, err := req.pipeline.RunContext(ctx, req.image, func(step algorithms.StepResult) {
+		log.Printf("Pipeline step %d: %s (%v)", step.Index+1, step.Operation, step.Elapsed)
+		if req.intermediate {
+			steps = append(steps, processResult{Algorithm: step.Operation, Image: step.Image, Elapsed: step.Elapsed, Metadata: step.Metadata})
+		}
+	})
+	if err != nil {
+		return processResult{}, nil, err
+	}
+	return processResult{Image: result, Elapsed: time.Since(start), Metadata: metadata}, steps, nil
+}
+
+// parseSteps 解析 steps 字段并按每个算子的参数说明解析参数
+func parseSteps(raw string) ([]algorithms.Step, error) {
+	var specs []pipelineStep
+	if err := json.Unmarshal([]byte(raw), &specs); err != nil {
+		return nil, fmt.Errorf("invalid steps: %v", err)
+	}
+	if len(specs) == 0 || len(specs) > maxPipelineSteps {
+		return nil, fmt.Errorf("a pipeline needs between 1 and %d steps", maxPipelineSteps)
+	}
+
+	steps := make([]algorithms.Step, len(specs))
+	for i, spec := range specs {
+		op, ok := algorithms.Lookup(spec.Algorithm)
+		if !ok {
+			return nil, fmt.Errorf("step %d: unknown algorithm %q", i+1, spec.Algorithm)
+		}
+		params, err := op.ParseParams(jsonLookup(spec.Params))
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", i+1, op.Name, err)
+		}
+		steps[i] = algorithms.Step{Operation: op.Name, Params: params}
+	}
+	return steps, nil
+}
+
+// jsonLookup 从 JSON 对象中读取参数值, 字符串去掉引号, 其余类型保留原始文本
+func jsonLookup(values map[string]json.RawMessage) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		raw, ok := values[name]
+		if !ok {
+			return "", false
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, true
+		}
+		return string(raw), true
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSteps 三个步骤都是无损的, 中间编码为 JPEG 会改变结果
+const testSteps = `[
+	{"algorithm": "Negative"},
+	{"algorithm": "Rescaling", "params": {"scalingFactor": 0.5}},
+	{"algorithm": "Power Law", "params": {"c": "2", "gamma": 1}}
+]`
+
+// runTestSteps 在内存中依次执行 testSteps, 作为参照
+func runTestSteps(t *testing.T) []image.Image {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(testPNG(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		name   string
+		params algorithms.Params
+	}{
+		{"Negative", nil},
+		{"Rescaling", algorithms.Params{"scalingFactor": 0.5}},
+		{"Power Law", algorithms.Params{"c": 2.0, "gamma": 1.0}},
+	}
+	var results []image.Image
+	for _, step := range steps {
+		img, err = algorithms.Apply(step.name, []image.Image{img}, step.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, img)
+	}
+	return results
+}
+
+// assertSamePixels 比较两张图像的 RGBA 值
+func assertSamePixels(t *testing.T, got, want image.Image) {
+	t.Helper()
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				t.Fatalf("(%d, %d) = %v, want %v", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
+
+// decodeEnvelopeImage 解码 JSON 返回中的 base64 图像
+func decodeEnvelopeImage(t *testing.T, envelope imageEnvelope) image.Image {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(envelope.Image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestPipelineEncodesOnce(t *testing.T) {
+	w := httptest.NewRecorder()
+	ProcessPipeline(w, newFormRequest(t, "/imageProcessing/pipeline", map[string]string{"steps": testSteps, "outputFormat": "png"}, "image/png"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	got, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := runTestSteps(t)
+	assertSamePixels(t, got, want[len(want)-1])
+}
+
+func TestPipelineIntermediate(t *testing.T) {
+	w := httptest.NewRecorder()
+	ProcessPipeline(w, newFormRequest(t, "/imageProcessing/pipeline", map[string]string{"steps": testSteps, "outputFormat": "png", "intermediate": "true"}, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+
+	var response pipelineEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	want := runTestSteps(t)
+	if len(response.Steps) != len(want) {
+		t.Fatalf("got %d steps, want %d", len(response.Steps), len(want))
+	}
+	for i, name := range []string{"Negative", "Rescaling", "Power Law"} {
+		step := response.Steps[i]
+		if step.Algorithm != name || step.Format != "png" {
+			t.Fatalf("step %d = %s (%s), want %s (png)", i+1, step.Algorithm, step.Format, name)
+		}
+		assertSamePixels(t, decodeEnvelopeImage(t, step), want[i])
+	}
+	assertSamePixels(t, decodeEnvelopeImage(t, response.imageEnvelope), want[len(want)-1])
+}
+
+func TestPipelineStepErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps string
+		want  string
+	}{
+		{"unknown algorithm", `[{"algorithm": "Negative"}, {"algorithm": "No Such Thing"}]`, `step 2: unknown algorithm "No Such Thing"`},
+		{"bad param", `[{"algorithm": "Negative"}, {"algorithm": "Negative"}, {"algorithm": "Rescaling", "params": {"scalingFactor": "abc"}}]`, "step 3 (Rescaling)"},
+		{"missing param", `[{"algorithm": "Rescaling"}]`, "step 1"},
+		{"no steps", `[]`, "between 1 and"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ProcessPipeline(w, newFormRequest(t, "/imageProcessing/pipeline", map[string]string{"steps": tt.steps}, ""))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			if body := w.Body.String(); !strings.Contains(body, tt.want) {
+				t.Fatalf("body = %q, want it to contain %q", body, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outputOptions 结果图像的编码方式
+type outputOptions struct {
+	algorithms.EncodeOptions
+	// explicit 表示客户端通过 outputFormat 指定了格式
+	explicit bool
+}
+
+// processResult 处理结果及其元数据
+type processResult struct {
+	Algorithm string
+	Image     image.Image
+	Elapsed   time.Duration
+	// Metadata 算子计算出的附加值, 例如自动选择的阈值
+	Metadata algorithms.Metadata
+}
+
+// metadataHeader 不返回 JSON 时, 算子的元数据以 JSON 放在这个响应头中
+const metadataHeader = "X-Image-Metadata"
+
+// imageEnvelope 是 Accept: application/json 时返回的结构
+type imageEnvelope struct {
+	Algorithm        string              `json:"algorithm,omitempty"`
+	Image            string              `json:"image"`
+	Format           string              `json:"format"`
+	ContentType      string              `json:"contentType"`
+	Width            int                 `json:"width"`
+	Height           int                 `json:"height"`
+	ProcessingTimeMs float64             `json:"processingTimeMs"`
+	Metadata         algorithms.Metadata `json:"metadata,omitempty"`
+}
+
+// 返回结果的方式
+type responseMode int
+
+const (
+	responseBase64 responseMode = iota // 旧的默认行为: text/plain 的 base64 文本
+	responseRaw                        // 原始图像字节
+	responseJSON                       // 包含 base64 数据和元数据的 JSON
+)
+
+// parseOutputOptions 读取 outputFormat 和 quality 表单字段
+func parseOutputOptions(r *http.Request) (outputOptions, error) {
+	out := outputOptions{EncodeOptions: algorithms.EncodeOptions{Format: algorithms.FormatJPEG}}
+
+	if name := r.FormValue("outputFormat"); name != "" {
+		format, err := algorithms.ParseFormat(name)
+		if err != nil {
+			return out, err
+		}
+		out.Format = format
+		out.explicit = true
+	}
+
+	if q := r.FormValue("quality"); q != "" {
+		quality, err := strconv.Atoi(q)
+		if err != nil || quality < 1 || quality > 100 {
+			return out, &algorithms.ParamError{Param: "quality", Reason: "must be an integer between 1 and 100"}
+		}
+		out.Quality = quality
+	}
+	return out, nil
+}
+
+// forImage 没有指定 outputFormat 时, 二值掩码以 PNG 输出, 每像素 1 位
+func (out *outputOptions) forImage(img image.Image) {
+	if !out.explicit && algorithms.IsMask(img) {
+		out.Format = algorithms.FormatPNG
+	}
+}
+
+// negotiate 根据 Accept 头选择返回方式. Accept 指定具体的图像类型且没有
+// outputFormat 时, 输出格式取自 Accept. 没有 Accept 头或接受 */* 时, 指定了
+// outputFormat 返回原始图像, 否则返回 base64 文本. 客户端不接受任何一种
+// 返回方式时 ok 为 false
+func negotiate(r *http.Request, out *outputOptions) (mode responseMode, ok bool) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return defaultMode(out), true
+	}
+	for _, accepted := range parseAccept(header) {
+		switch mediaType := accepted.mediaType; {
+		case mediaType == "application/json":
+			return responseJSON, true
+		case mediaType == "text/plain":
+			return responseBase64, true
+		case mediaType == "image/*":
+			return responseRaw, true
+		case strings.HasPrefix(mediaType, "image/"):
+			format, err := algorithms.ParseFormat(strings.TrimPrefix(mediaType, "image/"))
+			if err != nil || (out.explicit && format != out.Format) {
+				continue
+			}
+			out.Format = format
+			return responseRaw, true
+		case mediaType == "*/*":
+			return defaultMode(out), true
+		}
+	}
+	return 0, false
+}
+
+func defaultMode(out *outputOptions) responseMode {
+	if out.explicit {
+		return responseRaw
+	}
+	return responseBase64
+}
+
+// writeResult 编码结果图像并按 Accept 头返回
+func writeResult(w http.ResponseWriter, r *http.Request, result processResult, out outputOptions) {
+	out.forImage(result.Image)
+	mode, ok := negotiate(r, &out)
+	if !ok {
+		w.Header().Set("Vary", "Accept")
+		images := "image/*"
+		if out.explicit {
+			images = out.Format.ContentType()
+		}
+		http.Error(w, fmt.Sprintf("Accept must allow %s, application/json or text/plain", images), http.StatusNotAcceptable)
+		return
+	}
+	if mode == responseJSON {
+		envelope, err := newEnvelope(result, out)
+		if err != nil {
+			log.Println("Error encoding image:", err)
+			http.Error(w, "Error encoding image", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, envelope)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := algorithms.Encode(&buf, result.Image, out.EncodeOptions); err != nil {
+		log.Println("Error encoding image:", err)
+		http.Error(w, "Error encoding image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	setMetadataHeader(w, result.Metadata)
+	if mode == responseRaw {
+		w.Header().Set("Content-Type", out.Format.ContentType())
+		w.Write(buf.Bytes())
+		return
+	}
+
+	// 返回 base64 编码的图像
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(base64.StdEncoding.EncodeToString(buf.Bytes())))
+}
+
+// newEnvelope 编码图像并填写 JSON 返回结构
+func newEnvelope(result processResult, out outputOptions) (imageEnvelope, error) {
+	out.forImage(result.Image)
+	var buf bytes.Buffer
+	if err := algorithms.Encode(&buf, result.Image, out.EncodeOptions); err != nil {
+		return imageEnvelope{}, err
+	}
+
+	bounds := result.Image.Bounds()
+	return imageEnvelope{
+		Algorithm:        result.Algorithm,
+		Image:            base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Format:           string(out.Format),
+		ContentType:      out.Format.ContentType(),
+		Width:            bounds.Dx(),
+		Height:           bounds.Dy(),
+		ProcessingTimeMs: float64(result.Elapsed.Microseconds()) / 1000,
+		Metadata:         result.Metadata,
+	}, nil
+}
+
+// setMetadataHeader 把元数据写入响应头, 没有元数据时不写
+func setMetadataHeader(w http.ResponseWriter, metadata map[string]any) {
+	if len(metadata) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		log.Println("Error encoding metadata:", err)
+		return
+	}
+	w.Header().Set(metadataHeader, string(encoded))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	writeJSONStatus(w, http.StatusOK, v)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testPNG 返回 4x3 的 PNG 图像, 每个像素的值不同
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 5)
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newFormRequest 构造上传 testPNG 作为 image 的 multipart 请求, fields 为其余表单字段
+func newFormRequest(t *testing.T, target string, fields map[string]string, accept string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	part, err := mw.CreateFormFile("image", "in.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(testPNG(t))
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	return r
+}
+
+// newProcessRequest 构造调用 Negative 的请求
+func newProcessRequest(t *testing.T, accept, outputFormat string) *http.Request {
+	t.Helper()
+	fields := map[string]string{"algorithm": "Negative"}
+	if outputFormat != "" {
+		fields["outputFormat"] = outputFormat
+	}
+	return newFormRequest(t, "/imageProcessing/process", fields, accept)
+}
+
+func TestProcessNegotiation(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       string
+		outputFormat string
+		status       int
+		contentType  string
+		// format 是返回的图像格式, 对 JSON 为 format 字段
+		format string
+	}{
+		{"no accept", "", "", http.StatusOK, "text/plain", "jpeg"},
+		{"no accept with outputFormat", "", "png", http.StatusOK, "image/png", "png"},
+		{"any", "*/*", "", http.StatusOK, "text/plain", "jpeg"},
+		{"any with outputFormat", "*/*", "png", http.StatusOK, "image/png", "png"},
+		{"image wildcard", "image/*", "", http.StatusOK, "image/jpeg", "jpeg"},
+		{"image wildcard with outputFormat", "image/*", "png", http.StatusOK, "image/png", "png"},
+		{"specific image", "image/png", "", http.StatusOK, "image/png", "png"},
+		{"specific image matching outputFormat", "image/png", "png", http.StatusOK, "image/png", "png"},
+		{"json", "application/json", "", http.StatusOK, "application/json", "jpeg"},
+		{"json with outputFormat", "application/json", "png", http.StatusOK, "application/json", "png"},
+		{"text", "text/plain", "", http.StatusOK, "text/plain", "jpeg"},
+		{"q prefers json", "text/plain;q=0.5, application/json", "", http.StatusOK, "application/json", "jpeg"},
+		{"q prefers image", "application/json;q=0.2, image/png;q=0.9", "", http.StatusOK, "image/png", "png"},
+		{"q zero is refused", "image/png;q=0", "", http.StatusNotAcceptable, "", ""},
+		{"unsupported image type", "image/webp", "", http.StatusNotAcceptable, "", ""},
+		{"unsupported type", "text/html", "", http.StatusNotAcceptable, "", ""},
+		{"conflict", "image/jpeg", "png", http.StatusNotAcceptable, "", ""},
+		{"conflict falls back to json", "image/jpeg, application/json;q=0.1", "png", http.StatusOK, "application/json", "png"},
+		{"conflict falls back to text", "image/jpeg, text/plain;q=0.1", "png", http.StatusOK, "text/plain", "png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ProcessMixedAlgorithms(w, newProcessRequest(t, tt.accept, tt.outputFormat))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Fatalf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+
+			data := w.Body.Bytes()
+			switch tt.contentType {
+			case "application/json":
+				var envelope imageEnvelope
+				if err := json.Unmarshal(data, &envelope); err != nil {
+					t.Fatal(err)
+				}
+				if envelope.Format != tt.format {
+					t.Fatalf("format = %q, want %q", envelope.Format, tt.format)
+				}
+				return
+			case "text/plain":
+				decoded, err := base64.StdEncoding.DecodeString(string(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				data = decoded
+			}
+			_, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Fatalf("image format = %q, want %q", format, tt.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Finished reports whether the job will not change any more.
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+var (
+	// ErrQueueFull is returned by Submit when no more jobs can be queued.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrNotFound is returned for unknown or expired job IDs.
+	ErrNotFound = errors.New("job not found")
+	// ErrNotFinished is returned when asking for the result of a running job.
+	ErrNotFinished = errors.New("job has not finished")
+	// ErrClosed is returned by Submit after Close.
+	ErrClosed = errors.New("job queue is closed")
+)
+
+// Result is the encoded output of a job.
+type Result struct {
+	Data        []byte
+	ContentType string
+	// Metadata 任务计算出的附加值, 随结果一起返回
+	Metadata map[string]any
+}
+
+// Task does the work of a job. It should stop when ctx is cancelled and may
+// report progress from 0 to 1.
+type Task func(ctx context.Context, progress func(done float64)) (*Result, error)
+
+// Snapshot is a copy of a job's state that is safe to read and serialize.
+type Snapshot struct {
+	ID        string     `json:"id"`
+	Status    Status     `json:"status"`
+	Progress  float64    `json:"progress"`
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type job struct {
+	Snapshot
+	task   Task
+	result *Result
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Queue runs submitted tasks on a bounded pool of workers and keeps their
+// results until they expire.
+type Queue struct {
+	ttl            time.Duration
+	capacity       int
+	maxResultBytes int64
+
+	mu sync.Mutex
+	// ready 有任务排队或队列关闭时唤醒 worker
+	ready *sync.Cond
+	// queued 排队中的任务, 按提交顺序
+	queued []*job
+	jobs   map[string]*job
+	// finished 已结束的任务, 按结束时间排序, 也就是过期的顺序; resultBytes 是它们的结果总大小
+	finished    []*job
+	resultBytes int64
+	closed      bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewQueue starts workers goroutines. At most capacity jobs wait in the
+// queue, and finished jobs are removed ttl after they end. When the results
+// of finished jobs add up to more than maxResultBytes, the oldest finished
+// jobs are removed early; the most recent one is always kept. A
+// maxResultBytes of 0 means no limit.
+func NewQueue(workers, capacity int, ttl time.Duration, maxResultBytes int64) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	q := &Queue{
+		ttl:            ttl,
+		capacity:       capacity,
+		maxResultBytes: maxResultBytes,
+		jobs:           map[string]*job{},
+		stop:           make(chan struct{}),
+	}
+	q.ready = sync.NewCond(&q.mu)
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.janitor()
+	return q
+}
+
+// Submit queues task and returns the new job's state.
+func (q *Queue) Submit(task Task) (Snapshot, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return Snapshot{}, ErrClosed
+	}
+	if len(q.queued) >= q.capacity {
+		return Snapshot{}, ErrQueueFull
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		Snapshot: Snapshot{ID: newID(), Status: StatusQueued, CreatedAt: time.Now()},
+		task:     task,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	q.queued = append(q.queued, j)
+	q.jobs[j.ID] = j
+	q.ready.Signal()
+	return j.Snapshot, nil
+}
+
+// Get returns the current state of a job.
+func (q *Queue) Get(id string) (Snapshot, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.lookup(id)
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	return j.Snapshot, nil
+}
+
+// Result returns the output of a successfully finished job. For failed or
+// cancelled jobs the returned error describes why.
+func (q *Queue) Result(id string) (*Result, Snapshot, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.lookup(id)
+	if !ok {
+		return nil, Snapshot{}, ErrNotFound
+	}
+	if !j.Status.Finished() {
+		return nil, j.Snapshot, ErrNotFinished
+	}
+	return j.result, j.Snapshot, nil
+}
+
+// Cancel stops a queued or running job. A cancelled queued job frees its
+// place in the queue at once. Cancelling a finished job is a no-op.
+func (q *Queue) Cancel(id string) (Snapshot, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.lookup(id)
+	if !ok {
+		return Snapshot{}, ErrNotFound
+	}
+	if j.Status.Finished() {
+		return j.Snapshot, nil
+	}
+
+	j.cancel()
+	if j.Status == StatusQueued {
+		// 排队中的任务直接结束并让出队列中的位置
+		q.unqueue(j)
+		q.finish(j, nil, context.Canceled)
+	}
+	return j.Snapshot, nil
+}
+
+// Close stops accepting jobs, cancels the queued and running ones and waits
+// for the workers to exit.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	for _, j := range q.queued {
+		j.cancel()
+		q.finish(j, nil, context.Canceled)
+	}
+	q.queued = nil
+	for _, j := range q.jobs {
+		j.cancel()
+	}
+	q.ready.Broadcast()
+	close(q.stop)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		q.mu.Lock()
+		for len(q.queued) == 0 && !q.closed {
+			q.ready.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		j := q.queued[0]
+		q.queued[0] = nil
+		q.queued = q.queued[1:]
+		j.Status = StatusRunning
+		now := time.Now()
+		j.StartedAt = &now
+		q.mu.Unlock()
+
+		result, err := q.run(j)
+
+		q.mu.Lock()
+		q.finish(j, result, err)
+		q.mu.Unlock()
+	}
+}
+
+// run 执行任务. 任务中的 panic 转换为错误, 只让这个任务失败
+func (q *Queue) run(j *job) (result *Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return j.task(j.ctx, func(done float64) {
+		q.mu.Lock()
+		if done > j.Progress && done <= 1 {
+			j.Progress = done
+		}
+		q.mu.Unlock()
+	})
+}
+
+// lookup 返回未过期的任务, 调用方需持有 q.mu
+func (q *Queue) lookup(id string) (*job, bool) {
+	q.expire(time.Now())
+	j, ok := q.jobs[id]
+	return j, ok
+}
+
+// unqueue 从排队列表中删除 j, 调用方需持有 q.mu
+func (q *Queue) unqueue(j *job) {
+	for i, queued := range q.queued {
+		if queued == j {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			return
+		}
+	}
+}
+
+// finish 记录任务结果, 超过结果总大小限制时删除最早结束的任务. 调用方需持有 q.mu
+func (q *Queue) finish(j *job, result *Result, err error) {
+	now := time.Now()
+	expires := now.Add(q.ttl)
+	j.EndedAt, j.ExpiresAt = &now, &expires
+	switch {
+	case j.ctx.Err() != nil:
+		j.Status = StatusCancelled
+		j.Error = "cancelled"
+	case err != nil:
+		j.Status = StatusFailed
+		j.Error = err.Error()
+	default:
+		j.Status = StatusSucceeded
+		j.Progress = 1
+		j.result = result
+	}
+	j.cancel()
+
+	q.finished = append(q.finished, j)
+	if j.result != nil {
+		q.resultBytes += int64(len(j.result.Data))
+	}
+	for q.maxResultBytes > 0 && q.resultBytes > q.maxResultBytes && len(q.finished) > 1 {
+		q.removeOldest()
+	}
+}
+
+// expire 删除在 now 之前过期的任务, 调用方需持有 q.mu
+func (q *Queue) expire(now time.Time) {
+	for len(q.finished) > 0 && now.After(*q.finished[0].ExpiresAt) {
+		q.removeOldest()
+	}
+}
+
+// removeOldest 删除最早结束的任务, 调用方需持有 q.mu
+func (q *Queue) removeOldest() {
+	j := q.finished[0]
+	q.finished[0] = nil
+	q.finished = q.finished[1:]
+	delete(q.jobs, j.ID)
+	if j.result != nil {
+		q.resultBytes -= int64(len(j.result.Data))
+	}
+}
+
+// janitor 定期删除过期的任务, 没有请求时也能释放结果占用的内存
+func (q *Queue) janitor() {
+	defer q.wg.Done()
+	interval := q.ttl / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case now := <-ticker.C:
+			q.mu.Lock()
+			q.expire(now)
+			q.mu.Unlock()
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
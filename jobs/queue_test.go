@@ -0,0 +1,241 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestQueue 返回测试结束时关闭的队列
+func newTestQueue(t *testing.T, workers, capacity int, ttl time.Duration, maxResultBytes int64) *Queue {
+	t.Helper()
+	q := NewQueue(workers, capacity, ttl, maxResultBytes)
+	t.Cleanup(q.Close)
+	return q
+}
+
+// resultTask 立即返回 data
+func resultTask(data string) Task {
+	return func(ctx context.Context, progress func(float64)) (*Result, error) {
+		return &Result{Data: []byte(data), ContentType: "text/plain"}, nil
+	}
+}
+
+// blockingTask 报告一半进度后等待 release 或取消. started 在任务开始运行时关闭
+func blockingTask(started, release chan struct{}) Task {
+	return func(ctx context.Context, progress func(float64)) (*Result, error) {
+		progress(0.5)
+		close(started)
+		select {
+		case <-release:
+			return &Result{Data: []byte("done")}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// waitFinished 轮询任务状态直到任务结束
+func waitFinished(t *testing.T, q *Queue, id string) Snapshot {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status.Finished() {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Snapshot{}
+}
+
+func TestSubmit(t *testing.T) {
+	q := newTestQueue(t, 2, 4, time.Minute, 0)
+	job, err := q.Submit(resultTask("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.Status != StatusQueued {
+		t.Fatalf("submitted job = %+v", job)
+	}
+
+	job = waitFinished(t, q, job.ID)
+	if job.Status != StatusSucceeded || job.Progress != 1 || job.ExpiresAt == nil {
+		t.Fatalf("finished job = %+v", job)
+	}
+	result, _, err := q.Result(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Data) != "hello" {
+		t.Fatalf("result = %q, want %q", result.Data, "hello")
+	}
+
+	failed, err := q.Submit(func(ctx context.Context, progress func(float64)) (*Result, error) {
+		return nil, errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitFinished(t, q, failed.ID); job.Status != StatusFailed || job.Error != "boom" {
+		t.Fatalf("failed job = %+v", job)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	q := newTestQueue(t, 1, 4, time.Minute, 0)
+	if _, err := q.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of an unknown job: %v, want ErrNotFound", err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	job, err := q.Submit(blockingTask(started, release))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	job, err = q.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusRunning || job.Progress != 0.5 || job.StartedAt == nil {
+		t.Fatalf("running job = %+v", job)
+	}
+	if _, _, err := q.Result(job.ID); !errors.Is(err, ErrNotFinished) {
+		t.Fatalf("Result of a running job: %v, want ErrNotFinished", err)
+	}
+
+	close(release)
+	if job := waitFinished(t, q, job.ID); job.Status != StatusSucceeded {
+		t.Fatalf("finished job = %+v", job)
+	}
+}
+
+func TestCancelQueued(t *testing.T) {
+	q := newTestQueue(t, 1, 1, time.Minute, 0)
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	if _, err := q.Submit(blockingTask(started, release)); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ran := false
+	queued, err := q.Submit(func(ctx context.Context, progress func(float64)) (*Result, error) {
+		ran = true
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Submit(resultTask("")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit to a full queue: %v, want ErrQueueFull", err)
+	}
+
+	job, err := q.Cancel(queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusCancelled {
+		t.Fatalf("cancelled job = %+v", job)
+	}
+	// 取消的任务让出队列中的位置
+	if _, err := q.Submit(resultTask("")); err != nil {
+		t.Fatalf("Submit after cancelling the queued job: %v", err)
+	}
+	if _, job, err := q.Result(queued.ID); err != nil || job.Status != StatusCancelled {
+		t.Fatalf("Result of a cancelled job: %+v, %v", job, err)
+	}
+
+	release <- struct{}{}
+	q.Close()
+	if ran {
+		t.Fatal("the cancelled job ran")
+	}
+}
+
+func TestCancelRunning(t *testing.T) {
+	q := newTestQueue(t, 1, 4, time.Minute, 0)
+	started := make(chan struct{})
+	job, err := q.Submit(blockingTask(started, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	if _, err := q.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if job := waitFinished(t, q, job.ID); job.Status != StatusCancelled {
+		t.Fatalf("cancelled job = %+v", job)
+	}
+	// 取消已结束的任务不改变状态
+	if job, err := q.Cancel(job.ID); err != nil || job.Status != StatusCancelled {
+		t.Fatalf("cancelling again: %+v, %v", job, err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	q := newTestQueue(t, 1, 4, 20*time.Millisecond, 0)
+	job, err := q.Submit(resultTask("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitFinished(t, q, job.ID)
+
+	time.Sleep(time.Until(*job.ExpiresAt) + 10*time.Millisecond)
+	if _, err := q.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of an expired job: %v, want ErrNotFound", err)
+	}
+	if _, _, err := q.Result(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Result of an expired job: %v, want ErrNotFound", err)
+	}
+}
+
+func TestResultBytesLimit(t *testing.T) {
+	q := newTestQueue(t, 1, 4, time.Minute, 10)
+	var ids []string
+	for _, data := range []string{"aaaa", "bbbb", "cccc", "dddddddddddddddd"} {
+		job, err := q.Submit(resultTask(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFinished(t, q, job.ID)
+		ids = append(ids, job.ID)
+	}
+
+	// 前三个结果共 12 字节, 超过限制时删除最早的; 最后一个单独超过限制, 只保留它
+	for i, id := range ids {
+		_, _, err := q.Result(id)
+		if last := i == len(ids)-1; last != (err == nil) {
+			t.Errorf("job %d: %v", i, err)
+		}
+	}
+}
+
+func TestPanickingTask(t *testing.T) {
+	q := newTestQueue(t, 1, 4, time.Minute, 0)
+	job, err := q.Submit(func(ctx context.Context, progress func(float64)) (*Result, error) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitFinished(t, q, job.ID); job.Status != StatusFailed || job.Error != "job panicked: boom" {
+		t.Fatalf("panicked job = %+v", job)
+	}
+
+	// worker 仍然可以执行之后的任务
+	next, err := q.Submit(resultTask("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job := waitFinished(t, q, next.ID); job.Status != StatusSucceeded {
+		t.Fatalf("next job = %+v", job)
+	}
+}
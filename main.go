@@ -1,19 +1,28 @@
 package main
 
 import (
+	"WebAssembly-Based_Image_Processing_Tool/algorithms"
 	"WebAssembly-Based_Image_Processing_Tool/handlers"
+	"WebAssembly-Based_Image_Processing_Tool/jobs"
+	"flag"
 	"fmt"
 	"github.com/rs/cors"
 	"log"
 	"net/http"
+	"runtime"
+	"time"
 )
 
 func main() {
+	flag.IntVar(&algorithms.MaxKernelSize, "maxKernelSize", algorithms.MaxKernelSize, "自定义卷积核每个方向的最大尺寸")
+	flag.Parse()
+
 	// 配置 CORS 允许所有来源（可以根据需求限制来源）
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:63342"}, // 允许的前端域名
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
 		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"X-Image-Metadata"}, // 算子的元数据, 例如阈值
 		AllowCredentials: true,
 	})
 
@@ -24,6 +33,19 @@ func main() {
 	mux.HandleFunc("/imageProcessing/process/bitOperations", handlers.ProcessBitOperations)
 	mux.HandleFunc("/imageProcessing/process/convolution", handlers.ProcessConvolution)
 	mux.HandleFunc("/imageProcessing/process/transformations", handlers.ProcessTransformations)
+	mux.HandleFunc("/imageProcessing/process/threshold", handlers.ProcessThreshold)
+	mux.HandleFunc("/imageProcessing/process/morphology", handlers.ProcessMorphology)
+	mux.HandleFunc("/imageProcessing/process/geometry", handlers.ProcessGeometry)
+	mux.HandleFunc("/imageProcessing/operations", handlers.ListOperations)
+	mux.HandleFunc("/imageProcessing/pipeline", handlers.ProcessPipeline)
+	mux.HandleFunc("/imageProcessing/histogram", handlers.Histogram)
+
+	// 异步任务: 每个 CPU 一个 worker, 最多 64 个任务排队, 结果保留 30 分钟, 总共不超过 512 MB
+	jobHandlers := &handlers.JobHandlers{Queue: jobs.NewQueue(runtime.NumCPU(), 64, 30*time.Minute, 512<<20)}
+	mux.HandleFunc("POST /jobs", jobHandlers.Submit)
+	mux.HandleFunc("GET /jobs/{id}", jobHandlers.Status)
+	mux.HandleFunc("GET /jobs/{id}/result", jobHandlers.Result)
+	mux.HandleFunc("DELETE /jobs/{id}", jobHandlers.Cancel)
 
 	// 将处理器包装在 CORS 中
 	handler := c.Handler(mux)